@@ -43,7 +43,7 @@ func EnableCache() {
 
 var cache Cache
 
-func cacheLookup(t reflect.Type) map[string]reflectionInfo {
+func cacheLookup(t reflect.Type) *structInfo {
 	if cache == nil {
 		return nil
 	}
@@ -51,11 +51,11 @@ func cacheLookup(t reflect.Type) map[string]reflectionInfo {
 	if !exists {
 		return nil
 	}
-	result, _ := value.(map[string]reflectionInfo)
+	result, _ := value.(*structInfo)
 	return result
 }
 
-func cacheStore(t reflect.Type, fields map[string]reflectionInfo) {
+func cacheStore(t reflect.Type, fields *structInfo) {
 	if cache == nil {
 		return
 	}
@@ -50,12 +50,55 @@ func (m *MultipleError) Error() string {
 type ParseError struct {
 	Reason string
 	Path   string
+	// Err is the underlying error that caused the decode error, if any
+	Err error
 }
 
 func (p *ParseError) Error() string {
 	return fmt.Sprintf("parse error: %s in %s", p.Reason, p.Path)
 }
 
+// Unwrap returns the underlying error, allowing errors.Is and errors.As to inspect it.
+func (p *ParseError) Unwrap() error {
+	return p.Err
+}
+
+// NumberErrorReason describes why a JSON number cannot be decoded into a numeric type.
+type NumberErrorReason uint8
+
+const (
+	// NumberOverflow indicates the number is out of the range of the type.
+	NumberOverflow NumberErrorReason = iota
+
+	// NumberFraction indicates the number has a fraction, while the type is an integer type.
+	NumberFraction
+
+	// NumberNotIntegerLiteral indicates the number is not written as an integer literal, without a fraction
+	// or an exponent, as required by big.Int.
+	NumberNotIntegerLiteral
+)
+
+// NumberError indicates a JSON number that cannot be decoded into a numeric type without losing information.
+// When unmarshalling from a JSON map, it is wrapped by a ParseError.
+type NumberError struct {
+	// Value is the text of the JSON number
+	Value string
+	// Type is the type the number was decoded into
+	Type reflect.Type
+	// Reason tells why the number cannot be decoded into Type
+	Reason NumberErrorReason
+}
+
+func (n *NumberError) Error() string {
+	switch n.Reason {
+	case NumberFraction:
+		return fmt.Sprintf("number %s is not an integer, expected %s", n.Value, numberTypeName(n.Type))
+	case NumberNotIntegerLiteral:
+		return fmt.Sprintf("number %s is not an integer literal, expected %s", n.Value, numberTypeName(n.Type))
+	}
+	return fmt.Sprintf("number %s overflows %s", n.Value, numberTypeName(n.Type))
+}
+
 func newUnexpectedTypeParseError(expectedType reflect.Type, path []string) *ParseError {
 	return &ParseError{
 		Reason: fmt.Sprintf("expected type %s", externalTypeName(expectedType)),
@@ -70,6 +113,75 @@ func newUnsupportedTypeParseError(unsupportedType reflect.Type, path []string) *
 	}
 }
 
+func newInvalidQuotedParseError(expectedType reflect.Type, path []string) *ParseError {
+	return &ParseError{
+		Reason: fmt.Sprintf("expected quoted %s", externalTypeName(expectedType)),
+		Path:   strings.Join(path, "."),
+	}
+}
+
+func newArrayOverflowParseError(arrayType reflect.Type, path []string) *ParseError {
+	return &ParseError{
+		Reason: fmt.Sprintf("expected array of at most %d elements", arrayType.Len()),
+		Path:   strings.Join(path, "."),
+	}
+}
+
+func newInvalidBase64ParseError(err error, path []string) *ParseError {
+	return &ParseError{
+		Reason: err.Error(),
+		Path:   strings.Join(path, "."),
+	}
+}
+
+func newResultValueParseError(err error, path []string) *ParseError {
+	return &ParseError{
+		Reason: fmt.Sprintf("cannot convert result value: %s", err),
+		Path:   strings.Join(path, "."),
+	}
+}
+
+func newNumberParseError(err error, path []string) *ParseError {
+	return &ParseError{
+		Reason: err.Error(),
+		Path:   strings.Join(path, "."),
+		Err:    err,
+	}
+}
+
+func newTextUnmarshalerParseError(err error, path []string) *ParseError {
+	return &ParseError{
+		Reason: err.Error(),
+		Path:   strings.Join(path, "."),
+		Err:    err,
+	}
+}
+
+func newNumberOverflowError(value string, t reflect.Type) error {
+	return &NumberError{Value: value, Type: t, Reason: NumberOverflow}
+}
+
+func newNumberFractionError(value string, t reflect.Type) error {
+	return &NumberError{Value: value, Type: t, Reason: NumberFraction}
+}
+
+func newNumberNotIntegerError(value string, t reflect.Type) error {
+	return &NumberError{Value: value, Type: t, Reason: NumberNotIntegerLiteral}
+}
+
+// numberTypeName returns the name of the numeric kind of t, or the name of t itself for
+// struct types decoded from numbers, such as big.Int.
+func numberTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Struct {
+		return t.String()
+	}
+	return t.Kind().String()
+}
+
+func newInvalidNumberError(value string) error {
+	return fmt.Errorf("invalid number %s", value)
+}
+
 func addUnexpectedTypeLexerError(lexer *jlexer.Lexer, expectedType reflect.Type) {
 	lexer.AddNonFatalError(fmt.Errorf("expected type %s", externalTypeName(expectedType)))
 }
@@ -78,7 +190,33 @@ func addUnsupportedTypeLexerError(lexer *jlexer.Lexer, unsupportedType reflect.T
 	lexer.AddNonFatalError(fmt.Errorf("unsupported type %s", externalTypeName(unsupportedType)))
 }
 
+func addArrayOverflowLexerError(lexer *jlexer.Lexer, arrayType reflect.Type) {
+	lexer.AddNonFatalError(fmt.Errorf("expected array of at most %d elements", arrayType.Len()))
+}
+
+func addDuplicateKeyLexerError(lexer *jlexer.Lexer, key string) {
+	lexer.AddNonFatalError(fmt.Errorf("duplicate key %q", key))
+}
+
+func addInvalidUTF8LexerError(lexer *jlexer.Lexer, s string) {
+	lexer.AddNonFatalError(fmt.Errorf("invalid UTF-8 in string %q", s))
+}
+
+func addResultValueLexerError(lexer *jlexer.Lexer, err error) {
+	lexer.AddNonFatalError(fmt.Errorf("cannot convert result value: %s", err))
+}
+
+func addInvalidQuotedLexerError(lexer *jlexer.Lexer, expectedType reflect.Type) {
+	lexer.AddNonFatalError(fmt.Errorf("expected quoted %s", externalTypeName(expectedType)))
+}
+
 func externalTypeName(t reflect.Type) string {
+	if isNumberUnmarshaler(t) {
+		return "number"
+	}
+	if isTextUnmarshaler(t) {
+		return "string"
+	}
 	switch t.Kind() {
 	case reflect.String:
 		return "string"
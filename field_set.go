@@ -0,0 +1,51 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldSet records the known struct fields that were present in the input while unmarshalling,
+// including the fields of nested structs. It allows telling apart fields that were absent from
+// the input from fields that were set to their zero value, which is useful for PATCH-style updates.
+// Use WithFieldSet to have Unmarshal or UnmarshalFromJSONMap populate a FieldSet.
+// The zero value of FieldSet is an empty set ready to use.
+//
+// Fields are identified by their path - the JSON names of the fields and the keys of the maps
+// leading to them, joined by dots. For example, the field "field" of a struct stored in the field "child"
+// is identified by "child.field". Elements of slices and arrays do not add to the path.
+type FieldSet struct {
+	fields map[string]bool
+}
+
+// Has reports whether the field identified by path was present in the input, including as an explicit null.
+func (f *FieldSet) Has(path string) bool {
+	_, exists := f.fields[path]
+	return exists
+}
+
+// IsNull reports whether the field identified by path was present in the input as an explicit null.
+func (f *FieldSet) IsNull(path string) bool {
+	return f.fields[path]
+}
+
+// Paths returns the paths of all the fields that were present in the input, sorted.
+func (f *FieldSet) Paths() []string {
+	result := make([]string, 0, len(f.fields))
+	for path := range f.fields {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (f *FieldSet) add(path []string, isNull bool) {
+	if f.fields == nil {
+		f.fields = make(map[string]bool)
+	}
+	f.fields[strings.Join(path, ".")] = isNull
+}
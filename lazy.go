@@ -0,0 +1,97 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"encoding/json"
+	"github.com/mailru/easyjson/jlexer"
+	"reflect"
+	"sync"
+)
+
+// Lazy is a JSON value that is decoded only when it is first accessed. When the lazyUnknownFields option
+// is set, Unmarshal stores the values of fields that do not exist in the struct as *Lazy values, see
+// WithLazyUnknownFields. This allows paying for the decoding of only the unknown fields that are actually used.
+// Lazy values are safe for concurrent use.
+type Lazy struct {
+	raw     json.RawMessage
+	options *unmarshalOptions
+	once    sync.Once
+	value   interface{}
+	err     error
+}
+
+// Raw returns the raw JSON value.
+func (l *Lazy) Raw() json.RawMessage {
+	return l.raw
+}
+
+// Value returns the JSON value decoded into an interface{} value, the same way Unmarshal decodes the values of
+// unknown fields, following the options it was called with. The value is decoded on the first call to Value and
+// cached for the following ones.
+func (l *Lazy) Value() (interface{}, error) {
+	l.once.Do(func() {
+		d := &decoder{options: l.options, lexer: &jlexer.Lexer{Data: l.raw}}
+		value := d.interfaceValue()
+		d.lexer.Consumed()
+		if l.err = d.lexer.Error(); l.err == nil {
+			l.value = value
+		}
+	})
+	return l.value, l.err
+}
+
+// As decodes the JSON value into the value pointed to by v, the same way Unmarshal decodes struct fields,
+// following the options it was called with, such as the number mode and the duplicate key and invalid UTF-8
+// policies. Unlike Value, As does not cache its result, as each call may decode into a different type - the raw
+// JSON value is decoded on every call. If v is nil or not a pointer, As returns an ErrInvalidValue.
+func (l *Lazy) As(v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return ErrInvalidValue
+	}
+	target = target.Elem()
+	options := l.asOptions()
+	useMultipleErrors := options.mode == ModeAllowMultipleErrors
+	d := &decoder{options: options, lexer: &jlexer.Lexer{Data: l.raw, UseMultipleErrors: useMultipleErrors}}
+	if d.lexer.IsNull() && isNullable(target.Type()) {
+		d.lexer.Skip()
+		target.Set(reflect.Zero(target.Type()))
+	} else {
+		var existing reflect.Value
+		if !options.replaceExisting {
+			existing = target
+		}
+		value, _, valid := d.valueByReflectType(target.Type(), false, existing)
+		if valid {
+			assignValue(target, value)
+		}
+	}
+	d.lexer.Consumed()
+	if useMultipleErrors {
+		if errors := d.lexer.GetNonFatalErrors(); len(errors) > 0 {
+			return &MultipleLexerError{Errors: errors}
+		}
+		return nil
+	}
+	return d.lexer.Error()
+}
+
+// asOptions returns the options As decodes with - the options the value was created with, without
+// the ones that only apply to the result map and to the field set of Unmarshal.
+func (l *Lazy) asOptions() *unmarshalOptions {
+	options := *l.options
+	options.fieldSet = nil
+	options.nestedResults = false
+	if options.mode == ModeFailOverToOriginalValue {
+		options.mode = ModeAllowMultipleErrors
+	}
+	return &options
+}
+
+// MarshalJSON implements json.Marshaler, returning the raw JSON value.
+func (l *Lazy) MarshalJSON() ([]byte, error) {
+	return l.raw, nil
+}
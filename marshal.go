@@ -0,0 +1,288 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/mailru/easyjson/jwriter"
+	"reflect"
+	"sort"
+)
+
+// Marshal returns the JSON encoding of the struct pointed to by v, merged with the
+// entries of the extras map. It is the inverse of Unmarshal - passing it the struct and the
+// result map returned by Unmarshal produces a document holding all the original fields.
+// If v is nil or not a struct or a pointer to a struct, Marshal returns an ErrInvalidValue.
+//
+// Marshal follows the rules of json.Marshal with the following exceptions:
+// - The known fields of v are encoded first, in the order they are declared in the struct.
+// - Entries of the extras map whose keys are not known fields of v are encoded after the known fields,
+// sorted by key. Entries whose keys are known fields of v are ignored, so the typed values in v always win.
+// - Entries of the extras field of v, and of any nested struct, are encoded in the same way.
+func Marshal(v interface{}, extras map[string]interface{}) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, ErrInvalidValue
+	}
+	e := &encoder{w: &jwriter.Writer{}}
+	e.encodeStruct(value, extras)
+	return e.w.BuildBytes()
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	mapType           = reflect.TypeOf(map[string]interface{}{})
+)
+
+// startDetectingCyclesAfter is the nesting depth of pointers, maps and slices after which cycles are detected.
+// It follows json.Marshal, which avoids tracking the values being encoded unless they are deeply nested.
+const startDetectingCyclesAfter = 1000
+
+// cycleDetector tracks the pointers, maps and slices being encoded, in order to report cycles
+// rather than recursing until the stack overflows.
+type cycleDetector struct {
+	level uint
+	seen  map[cycleKey]struct{}
+}
+
+type cycleKey struct {
+	t   reflect.Type
+	ptr uintptr
+	len int
+}
+
+// enter records the pointer, map or slice v as being encoded. It returns an error if v is already
+// being encoded, meaning it holds a cycle. Otherwise, it must be followed by a call to leave.
+func (c *cycleDetector) enter(v reflect.Value) error {
+	c.level++
+	if c.level <= startDetectingCyclesAfter {
+		return nil
+	}
+	key := newCycleKey(v)
+	if _, exists := c.seen[key]; exists {
+		c.level--
+		return &json.UnsupportedValueError{Value: v, Str: fmt.Sprintf("encountered a cycle via %s", v.Type())}
+	}
+	if c.seen == nil {
+		c.seen = make(map[cycleKey]struct{})
+	}
+	c.seen[key] = struct{}{}
+	return nil
+}
+
+// leave records the pointer, map or slice v as no longer being encoded.
+func (c *cycleDetector) leave(v reflect.Value) {
+	if c.level > startDetectingCyclesAfter {
+		delete(c.seen, newCycleKey(v))
+	}
+	c.level--
+}
+
+func newCycleKey(v reflect.Value) cycleKey {
+	key := cycleKey{t: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	return key
+}
+
+// encoder writes the JSON encoding of values into w.
+type encoder struct {
+	w      *jwriter.Writer
+	cycles cycleDetector
+}
+
+func (e *encoder) encodeStruct(structValue reflect.Value, extras map[string]interface{}) {
+	w := e.w
+	info := mapStructTypeFields(structValue.Type())
+	w.RawByte('{')
+	first := true
+	for _, name := range info.names {
+		refInfo := info.fields[name]
+		field, exists := refInfo.existingField(structValue)
+		if !exists || !field.CanInterface() || (refInfo.omitEmpty && isEmptyValue(field)) {
+			continue
+		}
+		if !first {
+			w.RawByte(',')
+		}
+		first = false
+		w.String(name)
+		w.RawByte(':')
+		if refInfo.quoted {
+			e.encodeQuoted(field)
+		} else {
+			e.encodeValue(field)
+		}
+	}
+	var unknown map[string]interface{}
+	if info.extras != nil {
+		if field, exists := info.extras.existingField(structValue); exists {
+			unknown = field.Convert(mapType).Interface().(map[string]interface{})
+		}
+	}
+	keys := make([]string, 0, len(unknown)+len(extras))
+	for key := range unknown {
+		if _, exists := info.fields[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	for key := range extras {
+		if _, exists := info.fields[key]; exists {
+			continue
+		}
+		if _, exists := unknown[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, exists := extras[key]
+		if !exists {
+			value = unknown[key]
+		}
+		if !first {
+			w.RawByte(',')
+		}
+		first = false
+		w.String(key)
+		w.RawByte(':')
+		e.encodeValue(reflect.ValueOf(value))
+	}
+	w.RawByte('}')
+}
+
+// encodeValue writes the JSON encoding of v. Values that may hold structs are traversed in order to
+// encode their extras fields, while all other values are encoded using json.Marshal.
+func (e *encoder) encodeValue(v reflect.Value) {
+	w := e.w
+	if !v.IsValid() {
+		w.RawString("null")
+		return
+	}
+	t := v.Type()
+	if v.CanAddr() && (reflect.PtrTo(t).Implements(marshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		// follow json.Marshal, which calls pointer receiver marshalers of addressable values
+		w.Raw(json.Marshal(v.Addr().Interface()))
+		return
+	}
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) || !containsStruct(t, marshalerType, textMarshalerType) {
+		w.Raw(json.Marshal(v.Interface()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			w.RawString("null")
+			return
+		}
+		e.encodeValue(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			w.RawString("null")
+			return
+		}
+		if err := e.cycles.enter(v); err != nil {
+			w.Raw(nil, err)
+			return
+		}
+		defer e.cycles.leave(v)
+		e.encodeValue(v.Elem())
+	case reflect.Struct:
+		e.encodeStruct(v, nil)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				w.RawString("null")
+				return
+			}
+			if err := e.cycles.enter(v); err != nil {
+				w.Raw(nil, err)
+				return
+			}
+			defer e.cycles.leave(v)
+		}
+		w.RawByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				w.RawByte(',')
+			}
+			e.encodeValue(v.Index(i))
+		}
+		w.RawByte(']')
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String || t.Key().Implements(textMarshalerType) {
+			w.Raw(json.Marshal(v.Interface()))
+			return
+		}
+		if v.IsNil() {
+			w.RawString("null")
+			return
+		}
+		if err := e.cycles.enter(v); err != nil {
+			w.Raw(nil, err)
+			return
+		}
+		defer e.cycles.leave(v)
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		w.RawByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				w.RawByte(',')
+			}
+			w.String(key.String())
+			w.RawByte(':')
+			e.encodeValue(v.MapIndex(key))
+		}
+		w.RawByte('}')
+	default:
+		w.Raw(json.Marshal(v.Interface()))
+	}
+}
+
+// encodeQuoted writes the value of a field tagged with the string option as JSON inside a JSON string.
+func (e *encoder) encodeQuoted(v reflect.Value) {
+	w := e.w
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			w.RawString("null")
+			return
+		}
+		v = v.Elem()
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		w.Raw(nil, err)
+		return
+	}
+	w.String(string(data))
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-test/deep"
+	"strings"
+	"testing"
+)
+
+type pointerJSONMarshaler struct {
+	V int
+}
+
+func (*pointerJSONMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"custom"`), nil
+}
+
+type pointerTextMarshaler struct {
+	V int
+}
+
+func (*pointerTextMarshaler) MarshalText() ([]byte, error) {
+	return []byte("text"), nil
+}
+
+type pointerMarshalersStruct struct {
+	JSON pointerJSONMarshaler   `json:"json"`
+	Text pointerTextMarshaler   `json:"text"`
+	List []pointerJSONMarshaler `json:"list"`
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        interface{}
+		extras   map[string]interface{}
+		expected string
+		err      error
+	}{
+		{
+			name:     "known_fields_only",
+			v:        &nestedChild{Field: "foo"},
+			extras:   nil,
+			expected: `{"field":"foo"}`,
+		},
+		{
+			name:     "struct_value",
+			v:        nestedChild{Field: "foo"},
+			extras:   nil,
+			expected: `{"field":"foo"}`,
+		},
+		{
+			name:     "extras_merged_and_sorted",
+			v:        &nestedChild{Field: "foo"},
+			extras:   map[string]interface{}{"b": float64(1), "a": []interface{}{true}, "field": "ignored"},
+			expected: `{"field":"foo","a":[true],"b":1}`,
+		},
+		{
+			name: "omitempty",
+			v: &childStruct{
+				ChildField3: 3,
+			},
+			extras: nil,
+			expected: `{"child_field2":false,"child_field3":3,"child_field4":0,"child_field5":0,"child_field6":0,` +
+				`"child_field7":0,"child_field8":0,"child_field9":0,"child_field10":0,"child_field11":0,` +
+				`"child_field12":0,"child_field13":0,"child_field14":0,"child_field15":null,"child_field16":null,` +
+				`"child_field17":null,"child_field18":null,"child_field19":null,"child_field20":null,` +
+				`"child_field21":null,"child_field22":null,"child_field23":null,"child_field24":null,` +
+				`"child_field25":null,"child_field26":null,"child_field27":null,"child_field28":null,` +
+				`"child_field29":null,"child_field30":null,"child_field31":["","","",""]}`,
+		},
+		{
+			name: "nested_extras_fields",
+			v: &extrasParent{
+				Field:  "a",
+				Extras: map[string]interface{}{"extra": float64(1)},
+				Child: extrasChild{
+					Field:  "b",
+					Extras: map[string]interface{}{"extra": []interface{}{float64(2)}},
+				},
+				Children: []*extrasChild{{Field: "c", Extras: map[string]interface{}{"extra": "3"}}, nil},
+			},
+			extras: map[string]interface{}{"extra": float64(4), "other": float64(5)},
+			expected: `{"field":"a","child":{"field":"b","extra":[2]},"children":[{"field":"c","extra":"3"},null],` +
+				`"extra":4,"other":5}`,
+		},
+		{
+			name:     "quoted_fields",
+			v:        &quotedStruct{ID: 9007199254740993, Flag: true, Amount: 1.5, Name: "foo"},
+			extras:   nil,
+			expected: `{"id":"9007199254740993","ptr":null,"flag":"true","amount":"1.5","name":"\"foo\"","ignored":null}`,
+		},
+		{
+			name: "pointer_receiver_marshalers",
+			v: &pointerMarshalersStruct{
+				JSON: pointerJSONMarshaler{V: 1},
+				Text: pointerTextMarshaler{V: 2},
+				List: []pointerJSONMarshaler{{V: 3}},
+			},
+			extras:   nil,
+			expected: `{"json":"custom","text":"text","list":["custom"]}`,
+		},
+		{
+			name:     "pointer_receiver_marshalers_not_addressable",
+			v:        pointerMarshalersStruct{},
+			extras:   nil,
+			expected: `{"json":{"V":0},"text":{"V":0},"list":null}`,
+		},
+		{
+			name:   "invalid_value",
+			v:      "foo",
+			extras: nil,
+			err:    ErrInvalidValue,
+		},
+		{
+			name:   "nil_value",
+			v:      (*nestedChild)(nil),
+			extras: nil,
+			err:    ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Marshal(tt.v, tt.extras)
+			if err != tt.err {
+				t.Errorf("Marshal() unexpected error = %v, expected %v", err, tt.err)
+				return
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Marshal() unexpected data = %s, expected %s", data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalEmbedding(t *testing.T) {
+	t.Run("test_embedding_rules_match_json", func(t *testing.T) {
+		values := []*embeddingParent{
+			{Shadowed: "a", Tagged: embeddedValue{ValueField: "b"}},
+			{EmbeddedPtr: &EmbeddedPtr{PtrField: "a", Shadowed: "b"}, embeddedA: embeddedA{Ambiguous: "c", TaggedWins: "d"}},
+		}
+		for _, v := range values {
+			actual, err := Marshal(v, nil)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			expected, _ := json.Marshal(v)
+			if string(actual) != string(expected) {
+				t.Errorf("Marshal() mismatch, actual %s, expected %s", actual, expected)
+			}
+		}
+	})
+}
+
+type cyclicNode struct {
+	Name   string                 `json:"name"`
+	Next   *cyclicNode            `json:"next,omitempty"`
+	Extras map[string]interface{} `json:"-" marshmallow:",extras"`
+}
+
+func TestMarshalCycles(t *testing.T) {
+	pointerCycle := &cyclicNode{Name: "a"}
+	pointerCycle.Next = pointerCycle
+	mapCycle := &cyclicNode{Name: "b", Extras: map[string]interface{}{}}
+	mapCycle.Extras["self"] = mapCycle.Extras
+	sliceCycle := &cyclicNode{Name: "c", Extras: map[string]interface{}{}}
+	list := make([]interface{}, 1)
+	list[0] = list
+	sliceCycle.Extras["list"] = list
+	for name, v := range map[string]*cyclicNode{"pointer": pointerCycle, "map": mapCycle, "slice": sliceCycle} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Marshal(v, nil)
+			var unsupportedValueError *json.UnsupportedValueError
+			if !errors.As(err, &unsupportedValueError) || !strings.Contains(err.Error(), "encountered a cycle") {
+				t.Errorf("Marshal() unexpected error = %v", err)
+			}
+		})
+	}
+	t.Run("deep_acyclic", func(t *testing.T) {
+		root := &cyclicNode{Name: "root"}
+		node := root
+		for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+			node.Next = &cyclicNode{Name: "node"}
+			node = node.Next
+		}
+		data, err := Marshal(root, nil)
+		if err != nil {
+			t.Fatalf("Marshal() unexpected error = %v", err)
+		}
+		expected, _ := json.Marshal(root)
+		if string(data) != string(expected) {
+			t.Errorf("Marshal() mismatch with json.Marshal")
+		}
+	})
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	t.Run("test_round_trip", func(t *testing.T) {
+		input := toMap(buildParentStruct())
+		for k, v := range extraData {
+			input[k] = v
+		}
+		data, err := json.Marshal(input)
+		if err != nil {
+			t.Errorf("could not marshal input: %v", err)
+			return
+		}
+		p := parentStruct{}
+		result, err := Unmarshal(data, &p)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+			return
+		}
+		data, err = Marshal(&p, result)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+			return
+		}
+		actual := make(map[string]interface{})
+		err = json.Unmarshal(data, &actual)
+		if err != nil {
+			t.Errorf("invalid JSON output %v", err)
+			return
+		}
+		expected := toMap(&p)
+		for k, v := range extraData {
+			expected[k] = v
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("Marshal() mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
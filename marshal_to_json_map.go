@@ -0,0 +1,396 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/mailru/easyjson/jlexer"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// MarshalerToJSONMap is the interface implemented by types
+// that can marshal themselves into a JSON map value.
+// It is the counterpart of UnmarshalerFromJSONMap - while marshalling to a JSON map, a json.Marshaler
+// implementation requires encoding to []byte and decoding back, which will significantly hurt performance.
+// Thus, if you wish to implement a custom marshalling on a type that is being marshalled to a JSON map,
+// you need to implement MarshalerToJSONMap interface. The returned value should only contain
+// the following types: bool, string, float64, []interface{}, and map[string]interface{}.
+type MarshalerToJSONMap interface {
+	MarshalJSONToMap() (interface{}, error)
+}
+
+// MarshalToJSONMap returns the JSON map representation of the struct pointed to by v, merged with the
+// entries of the extras map. It is the inverse of UnmarshalFromJSONMap.
+// If v is nil or not a struct or a pointer to a struct, MarshalToJSONMap returns an ErrInvalidValue.
+//
+// MarshalToJSONMap follows the rules of Marshal, except that instead of raw bytes, it returns a JSON map,
+// meaning it only contains the following types: bool, string, float64, json.Number, []interface{}, and
+// map[string]interface{}. json.Number and *OrderedMap values are kept as is.
+// Entries of the extras map holding values of other types are converted as well.
+func MarshalToJSONMap(v interface{}, extras map[string]interface{}) (map[string]interface{}, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, ErrInvalidValue
+	}
+	e := &jsonMapEncoder{mode: NumberModeFloat64}
+	return e.structToJSONMap(value, extras)
+}
+
+var (
+	marshalerToJSONMapType = reflect.TypeOf((*MarshalerToJSONMap)(nil)).Elem()
+	numberType             = reflect.TypeOf(json.Number(""))
+)
+
+// jsonMapEncoder converts values into their JSON map representation, representing Go numbers according to mode.
+type jsonMapEncoder struct {
+	mode   NumberMode
+	cycles cycleDetector
+}
+
+func (e *jsonMapEncoder) structToJSONMap(structValue reflect.Value, extras map[string]interface{}) (map[string]interface{}, error) {
+	info := mapStructTypeFields(structValue.Type())
+	result := make(map[string]interface{}, len(info.names)+len(extras))
+	if info.extras != nil {
+		if field, exists := info.extras.existingField(structValue); exists {
+			for _, key := range field.MapKeys() {
+				value, err := e.jsonMapValue(field.MapIndex(key).Interface())
+				if err != nil {
+					return nil, err
+				}
+				result[key.String()] = value
+			}
+		}
+	}
+	for key, extra := range extras {
+		value, err := e.jsonMapValue(extra)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	for _, name := range info.names {
+		refInfo := info.fields[name]
+		field, exists := refInfo.existingField(structValue)
+		if !exists || !field.CanInterface() || (refInfo.omitEmpty && isEmptyValue(field)) {
+			delete(result, name)
+			continue
+		}
+		var value interface{}
+		var err error
+		if refInfo.quoted {
+			value, err = quotedToJSONMap(field)
+		} else {
+			value, err = e.valueToJSONMap(field)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+// quotedToJSONMap returns the value of a field tagged with the string option as JSON inside a string.
+func quotedToJSONMap(v reflect.Value) (interface{}, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// jsonMapValue converts an arbitrary value into its JSON map representation, representing Go numbers
+// according to the given number mode. Values that are already of JSON map types are returned as is,
+// and so are slices and maps of JSON map types unless some of their elements need to be converted,
+// in which case a converted copy is returned.
+func jsonMapValue(v interface{}, mode NumberMode) (interface{}, error) {
+	e := &jsonMapEncoder{mode: mode}
+	return e.jsonMapValue(v)
+}
+
+func (e *jsonMapEncoder) jsonMapValue(v interface{}) (interface{}, error) {
+	result, _, err := e.convertJSONMapValue(v)
+	return result, err
+}
+
+// convertJSONMapValue converts v into its JSON map representation, reporting whether the result differs from v.
+func (e *jsonMapEncoder) convertJSONMapValue(v interface{}) (interface{}, bool, error) {
+	switch value := v.(type) {
+	case nil, bool, string, json.Number:
+		return v, false, nil
+	case float64:
+		converted := floatToJSONMap(value, e.mode)
+		_, same := converted.(float64)
+		return converted, !same, nil
+	case int64:
+		return intToJSONMap(value, e.mode), e.mode != NumberModeInt64IfIntegral, nil
+	case []interface{}:
+		if err := e.cycles.enter(reflect.ValueOf(value)); err != nil {
+			return nil, false, err
+		}
+		defer e.cycles.leave(reflect.ValueOf(value))
+		var result []interface{}
+		for i, element := range value {
+			converted, changed, err := e.convertJSONMapValue(element)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && result == nil {
+				result = make([]interface{}, len(value))
+				copy(result, value)
+			}
+			if result != nil {
+				result[i] = converted
+			}
+		}
+		if result == nil {
+			return v, false, nil
+		}
+		return result, true, nil
+	case map[string]interface{}:
+		if err := e.cycles.enter(reflect.ValueOf(value)); err != nil {
+			return nil, false, err
+		}
+		defer e.cycles.leave(reflect.ValueOf(value))
+		var result map[string]interface{}
+		for key, element := range value {
+			converted, changed, err := e.convertJSONMapValue(element)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && result == nil {
+				result = make(map[string]interface{}, len(value))
+				for k, e := range value {
+					result[k] = e
+				}
+			}
+			if result != nil {
+				result[key] = converted
+			}
+		}
+		if result == nil {
+			return v, false, nil
+		}
+		return result, true, nil
+	case *OrderedMap:
+		if value == nil {
+			return nil, true, nil
+		}
+		if err := e.cycles.enter(reflect.ValueOf(value)); err != nil {
+			return nil, false, err
+		}
+		defer e.cycles.leave(reflect.ValueOf(value))
+		var result *OrderedMap
+		for i, key := range value.keys {
+			converted, changed, err := e.convertJSONMapValue(value.values[key])
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && result == nil {
+				result = &OrderedMap{}
+				for _, k := range value.keys[:i] {
+					result.Set(k, value.values[k])
+				}
+			}
+			if result != nil {
+				result.Set(key, converted)
+			}
+		}
+		if result == nil {
+			return v, false, nil
+		}
+		return result, true, nil
+	}
+	result, err := e.valueToJSONMap(reflect.ValueOf(v))
+	return result, true, err
+}
+
+func (e *jsonMapEncoder) valueToJSONMap(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	t := v.Type()
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	if t.Implements(marshalerToJSONMapType) {
+		return v.Interface().(MarshalerToJSONMap).MarshalJSONToMap()
+	}
+	if v.CanAddr() && reflect.PtrTo(t).Implements(marshalerToJSONMapType) {
+		return v.Addr().Interface().(MarshalerToJSONMap).MarshalJSONToMap()
+	}
+	if t.Implements(marshalerType) {
+		return e.customMarshalerToJSONMap(v.Interface().(json.Marshaler))
+	}
+	if v.CanAddr() && reflect.PtrTo(t).Implements(marshalerType) {
+		return e.customMarshalerToJSONMap(v.Addr().Interface().(json.Marshaler))
+	}
+	if t == numberType {
+		return v.Interface(), nil
+	}
+	if t.Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intToJSONMap(v.Int(), e.mode), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintToJSONMap(v.Uint(), e.mode), nil
+	case reflect.Float32:
+		// follow json.Marshal, which formats float32 values using their shortest 32-bit representation
+		return convertNumber(strconv.FormatFloat(v.Float(), 'g', -1, 32), e.mode)
+	case reflect.Float64:
+		return floatToJSONMap(v.Float(), e.mode), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return e.valueToJSONMap(v.Elem())
+	case reflect.Ptr:
+		if err := e.cycles.enter(v); err != nil {
+			return nil, err
+		}
+		defer e.cycles.leave(v)
+		return e.valueToJSONMap(v.Elem())
+	case reflect.Struct:
+		return e.structToJSONMap(v, nil)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+		}
+		if err := e.cycles.enter(v); err != nil {
+			return nil, err
+		}
+		defer e.cycles.leave(v)
+		return e.arrayToJSONMap(v)
+	case reflect.Array:
+		return e.arrayToJSONMap(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if err := e.cycles.enter(v); err != nil {
+			return nil, err
+		}
+		defer e.cycles.leave(v)
+		result := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := mapKeyToString(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			value, err := e.valueToJSONMap(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+		}
+		return result, nil
+	}
+	return nil, &json.UnsupportedTypeError{Type: t}
+}
+
+func (e *jsonMapEncoder) arrayToJSONMap(v reflect.Value) (interface{}, error) {
+	l := v.Len()
+	result := make([]interface{}, l)
+	for i := 0; i < l; i++ {
+		value, err := e.valueToJSONMap(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// mapKeyToString returns the JSON object key representation of a map key, following the rules of json.Marshal.
+func mapKeyToString(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.Type().Implements(textMarshalerType) {
+		if key.Kind() == reflect.Ptr && key.IsNil() {
+			return "", nil
+		}
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", &json.UnsupportedTypeError{Type: key.Type()}
+}
+
+func (e *jsonMapEncoder) customMarshalerToJSONMap(marshaler json.Marshaler) (interface{}, error) {
+	data, err := marshaler.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{options: &unmarshalOptions{numberMode: e.mode}, lexer: &jlexer.Lexer{Data: data}}
+	result := d.interfaceValue()
+	d.lexer.Consumed()
+	return result, d.lexer.Error()
+}
+
+// intToJSONMap returns the JSON map representation of the integer n according to the given number mode.
+func intToJSONMap(n int64, mode NumberMode) interface{} {
+	switch mode {
+	case NumberModeJSONNumber:
+		return json.Number(strconv.FormatInt(n, 10))
+	case NumberModeInt64IfIntegral:
+		return n
+	}
+	return float64(n)
+}
+
+// uintToJSONMap returns the JSON map representation of the unsigned integer n according to the given number mode.
+// With NumberModeInt64IfIntegral, integers beyond the int64 range are represented as float64 values.
+func uintToJSONMap(n uint64, mode NumberMode) interface{} {
+	if n <= math.MaxInt64 {
+		return intToJSONMap(int64(n), mode)
+	}
+	if mode == NumberModeJSONNumber {
+		return json.Number(strconv.FormatUint(n, 10))
+	}
+	return float64(n)
+}
+
+// floatToJSONMap returns the JSON map representation of f according to the given number mode.
+func floatToJSONMap(f float64, mode NumberMode) interface{} {
+	switch mode {
+	case NumberModeJSONNumber:
+		return json.Number(formatFloat(f))
+	case NumberModeInt64IfIntegral:
+		if n, ok := integralInt64(f); ok {
+			return n
+		}
+	}
+	return f
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-test/deep"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalToJSONMap(t *testing.T) {
+	timestamp := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		v        interface{}
+		extras   map[string]interface{}
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "known_fields_only",
+			v:        &nestedChild{Field: "foo"},
+			extras:   nil,
+			expected: map[string]interface{}{"field": "foo"},
+		},
+		{
+			name:     "extras_merged",
+			v:        nestedChild{Field: "foo"},
+			extras:   map[string]interface{}{"a": []interface{}{true}, "b": 12, "field": "ignored"},
+			expected: map[string]interface{}{"field": "foo", "a": []interface{}{true}, "b": float64(12)},
+		},
+		{
+			name: "nested_extras_fields",
+			v: &extrasParent{
+				Field:  "a",
+				Extras: map[string]interface{}{"extra": float64(1)},
+				Child: extrasChild{
+					Field:  "b",
+					Extras: map[string]interface{}{"extra": []interface{}{float64(2)}},
+				},
+				Children: []*extrasChild{nil},
+			},
+			extras: map[string]interface{}{"other": float64(5)},
+			expected: map[string]interface{}{
+				"field":    "a",
+				"child":    map[string]interface{}{"field": "b", "extra": []interface{}{float64(2)}},
+				"children": []interface{}{nil},
+				"extra":    float64(1),
+				"other":    float64(5),
+			},
+		},
+		{
+			name: "special_types",
+			v: &marshalToJSONMapStruct{
+				Custom:    marshalerToJSONMap{},
+				Timestamp: timestamp,
+				Bytes:     []byte("foo"),
+				IntMap:    map[int]int8{1: 2},
+				Array:     [2]uint{3},
+			},
+			extras: nil,
+			expected: map[string]interface{}{
+				"custom":    "MarshalJSONToMap called",
+				"timestamp": "2022-01-02T03:04:05Z",
+				"bytes":     "Zm9v",
+				"int_map":   map[string]interface{}{"1": float64(2)},
+				"array":     []interface{}{float64(3), float64(0)},
+				"ptr":       nil,
+			},
+		},
+		{
+			name:   "quoted_fields",
+			v:      &quotedStruct{ID: 9007199254740993, Flag: true, Amount: 1.5, Name: "foo"},
+			extras: nil,
+			expected: map[string]interface{}{
+				"id": "9007199254740993", "ptr": nil, "flag": "true", "amount": "1.5", "name": `"foo"`, "ignored": nil,
+			},
+		},
+		{
+			name:   "invalid_value",
+			v:      12,
+			extras: nil,
+			err:    ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := MarshalToJSONMap(tt.v, tt.extras)
+			if err != tt.err {
+				t.Errorf("MarshalToJSONMap() unexpected error = %v, expected %v", err, tt.err)
+				return
+			}
+			if diff := deep.Equal(actual, tt.expected); diff != nil {
+				t.Errorf("MarshalToJSONMap() mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestMarshalToJSONMapMatchesJSON(t *testing.T) {
+	t.Run("test_matches_json_marshal", func(t *testing.T) {
+		p := buildParentStruct()
+		actual, err := MarshalToJSONMap(p, extraData)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+			return
+		}
+		expected := toMap(p)
+		for k, v := range extraData {
+			expected[k] = v
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("MarshalToJSONMap() mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		actualStruct := &parentStruct{}
+		_, err = UnmarshalFromJSONMap(actual, actualStruct)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+type marshalToJSONMapStruct struct {
+	Custom    marshalerToJSONMap `json:"custom"`
+	Timestamp time.Time          `json:"timestamp"`
+	Bytes     []byte             `json:"bytes"`
+	IntMap    map[int]int8       `json:"int_map"`
+	Array     [2]uint            `json:"array"`
+	Ptr       *json.Number       `json:"ptr"`
+}
+
+type marshalerToJSONMap struct{}
+
+func (c marshalerToJSONMap) MarshalJSONToMap() (interface{}, error) {
+	return "MarshalJSONToMap called", nil
+}
+
+func TestMarshalToJSONMapCycles(t *testing.T) {
+	pointerCycle := &cyclicNode{Name: "a"}
+	pointerCycle.Next = pointerCycle
+	mapCycle := &cyclicNode{Name: "b", Extras: map[string]interface{}{}}
+	mapCycle.Extras["self"] = mapCycle.Extras
+	sliceCycle := &cyclicNode{Name: "c", Extras: map[string]interface{}{}}
+	list := make([]interface{}, 1)
+	list[0] = list
+	sliceCycle.Extras["list"] = list
+	for name, v := range map[string]*cyclicNode{"pointer": pointerCycle, "map": mapCycle, "slice": sliceCycle} {
+		t.Run(name, func(t *testing.T) {
+			_, err := MarshalToJSONMap(v, nil)
+			var unsupportedValueError *json.UnsupportedValueError
+			if !errors.As(err, &unsupportedValueError) || !strings.Contains(err.Error(), "encountered a cycle") {
+				t.Errorf("MarshalToJSONMap() unexpected error = %v", err)
+			}
+		})
+	}
+	t.Run("deep_acyclic", func(t *testing.T) {
+		root := &cyclicNode{Name: "root"}
+		node := root
+		for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+			node.Next = &cyclicNode{Name: "node"}
+			node = node.Next
+		}
+		result, err := MarshalToJSONMap(root, nil)
+		if err != nil {
+			t.Fatalf("MarshalToJSONMap() unexpected error = %v", err)
+		}
+		data, _ := json.Marshal(root)
+		expected := make(map[string]interface{})
+		if err = json.Unmarshal(data, &expected); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Errorf("MarshalToJSONMap() mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// UnmarshalerFromJSONNumber is the interface implemented by types that can unmarshal
+// themselves from the text of a JSON number. Implement it on arbitrary-precision or decimal
+// types to receive the original number literal rather than a float64 approximation of it.
+// Both Unmarshal and UnmarshalFromJSONMap call it for JSON numbers, and reject any other
+// JSON type except null. It takes precedence over json.Unmarshaler, UnmarshalerFromJSONMap
+// and encoding.TextUnmarshaler.
+//
+// The math/big types big.Int and big.Float are decoded the same way, from the text of the number.
+// Like big.Int.UnmarshalJSON, big.Int values only accept integer literals, without a fraction or an exponent.
+type UnmarshalerFromJSONNumber interface {
+	UnmarshalJSONFromNumber(number json.Number) error
+}
+
+var (
+	numberUnmarshalerType = reflect.TypeOf((*UnmarshalerFromJSONNumber)(nil)).Elem()
+	bigIntType            = reflect.TypeOf(big.Int{})
+	bigFloatType          = reflect.TypeOf(big.Float{})
+)
+
+// isNumberUnmarshaler reports whether values of type t are decoded from the text of JSON numbers,
+// either using UnmarshalerFromJSONNumber or as big.Int and big.Float values.
+func isNumberUnmarshaler(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	if t.Implements(numberUnmarshalerType) || reflect.PtrTo(t).Implements(numberUnmarshalerType) {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == bigIntType || t == bigFloatType
+}
+
+// unmarshalNumber returns a value of type t decoded from the JSON number text s. Pointer types are
+// allocated, following json.Unmarshal.
+func unmarshalNumber(t reflect.Type, s string) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		value := reflect.New(t.Elem())
+		err := unmarshalNumberInto(value, s)
+		return value.Interface(), err
+	}
+	value := reflect.New(t)
+	err := unmarshalNumberInto(value, s)
+	return value.Elem().Interface(), err
+}
+
+// unmarshalNumberInto decodes the JSON number text s into the value pointed to by ptr.
+func unmarshalNumberInto(ptr reflect.Value, s string) error {
+	switch target := ptr.Interface().(type) {
+	case UnmarshalerFromJSONNumber:
+		return target.UnmarshalJSONFromNumber(json.Number(s))
+	case *big.Int:
+		// follow big.Int.UnmarshalJSON, which rejects fractions and exponents rather than expanding them,
+		// as a short exponent literal may expand into an arbitrarily large integer
+		if _, ok := target.SetString(s, 10); ok {
+			return nil
+		}
+		return newNumberNotIntegerError(s, bigIntType)
+	case *big.Float:
+		f, err := parseBigFloat(s)
+		if err != nil {
+			return err
+		}
+		target.Set(f)
+		return nil
+	}
+	return newInvalidNumberError(s)
+}
+
+// parseBigFloat parses the JSON number text s into a big.Float with enough precision to
+// hold all of its decimal digits, and at least the precision of a float64.
+func parseBigFloat(s string) (*big.Float, error) {
+	prec := uint(math.Ceil(float64(len(s)) * math.Log2(10)))
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, newInvalidNumberError(s)
+	}
+	return f, nil
+}
+
+// numberText returns the text of a JSON number held by a JSON map value, which is either a float64,
+// a json.Number or an int64, depending on the number mode it was decoded with. Integral float64 values
+// are formatted without an exponent, so that they can be decoded into big.Int values.
+func numberText(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case float64:
+		if value == math.Trunc(value) && !math.IsInf(value, 0) {
+			return strconv.FormatFloat(value, 'f', -1, 64), true
+		}
+		return formatFloat(value), true
+	case json.Number:
+		return string(value), true
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	}
+	return "", false
+}
@@ -26,6 +26,95 @@ const (
 	ModeFailOverToOriginalValue
 )
 
+// NumberMode dictates how JSON numbers are represented in the result map and in interface{} values.
+// Each mode is self documented below.
+type NumberMode uint8
+
+const (
+	// NumberModeFloat64 is the default number mode. It makes unmarshalling represent numbers as float64
+	// values, the same way json.Unmarshal does. Note that integers above 2^53 lose precision in this mode.
+	NumberModeFloat64 NumberMode = iota
+
+	// NumberModeJSONNumber mode makes unmarshalling represent numbers as json.Number values,
+	// holding the original number text.
+	NumberModeJSONNumber
+
+	// NumberModeInt64IfIntegral mode makes unmarshalling represent integral numbers that fit in an int64
+	// as int64 values, and all other numbers as float64 values.
+	NumberModeInt64IfIntegral
+)
+
+// ArrayOverflowPolicy dictates how JSON arrays holding more elements than a fixed-size Go array are handled.
+// Each policy is self documented below.
+type ArrayOverflowPolicy uint8
+
+const (
+	// ArrayOverflowDrop is the default array overflow policy. It makes unmarshalling drop the
+	// extra elements, the same way json.Unmarshal does.
+	ArrayOverflowDrop ArrayOverflowPolicy = iota
+
+	// ArrayOverflowError policy makes unmarshalling report an error for arrays holding extra elements.
+	// The error is handled according to the unmarshalling mode - with ModeFailOverToOriginalValue,
+	// the full original array is placed in the result data.
+	ArrayOverflowError
+)
+
+// DuplicateKeyPolicy dictates how keys appearing more than once in the same JSON object are handled.
+// Each policy is self documented below.
+type DuplicateKeyPolicy uint8
+
+const (
+	// DuplicateLast is the default duplicate key policy. It makes unmarshalling use the last value
+	// of a duplicate key, the same way json.Unmarshal does.
+	DuplicateLast DuplicateKeyPolicy = iota
+
+	// DuplicateFirst policy makes unmarshalling use the first value of a duplicate key, skipping the others.
+	DuplicateFirst
+
+	// DuplicateError policy makes unmarshalling report an error for every duplicate key.
+	// The error is handled according to the unmarshalling mode - with ModeAllowMultipleErrors and
+	// ModeFailOverToOriginalValue, the first value of the key is kept.
+	DuplicateError
+)
+
+// InvalidUTF8Policy dictates how JSON strings holding invalid UTF-8 are handled.
+// Each policy is self documented below.
+type InvalidUTF8Policy uint8
+
+const (
+	// InvalidUTF8Pass is the default invalid UTF-8 policy. It makes unmarshalling keep invalid UTF-8
+	// bytes as they are. Escaped UTF-16 surrogates that are not part of a valid pair are decoded as U+FFFD.
+	InvalidUTF8Pass InvalidUTF8Policy = iota
+
+	// InvalidUTF8Replace policy makes unmarshalling replace every invalid UTF-8 byte with U+FFFD,
+	// the same way json.Unmarshal does.
+	InvalidUTF8Replace
+
+	// InvalidUTF8Error policy makes unmarshalling report an error for every string holding invalid UTF-8 bytes
+	// or an escaped UTF-16 surrogate that is not part of a valid pair. The error is handled according to the
+	// unmarshalling mode - with ModeAllowMultipleErrors and ModeFailOverToOriginalValue, the string is kept
+	// with its invalid bytes replaced by U+FFFD.
+	InvalidUTF8Error
+)
+
+// ResultContent dictates which fields are stored in the result map.
+// Each content is self documented below.
+type ResultContent uint8
+
+const (
+	// ResultAll is the default result content. It makes unmarshalling store all fields in the result map,
+	// both the fields that exist in the struct and the ones that do not.
+	ResultAll ResultContent = iota
+
+	// ResultUnknownOnly content makes unmarshalling store only the fields that do not exist in the struct
+	// in the result map. With ModeFailOverToOriginalValue, erroneous known fields are omitted as well.
+	ResultUnknownOnly
+
+	// ResultKnownOnly content makes unmarshalling store only the fields that exist in the struct in the
+	// result map, skipping the decoding of other fields unless the struct has an extras field.
+	ResultKnownOnly
+)
+
 // WithMode is an UnmarshalOption function to set the unmarshalling mode.
 func WithMode(mode Mode) UnmarshalOption {
 	return func(options *unmarshalOptions) {
@@ -33,6 +122,30 @@ func WithMode(mode Mode) UnmarshalOption {
 	}
 }
 
+// WithResultContent is an UnmarshalOption function to set the result content.
+// The result content applies to the result map and, with WithNestedResults, to the result maps of nested structs.
+// It complements WithSkipPopulateStruct - use ResultUnknownOnly when the struct is used and only its leftovers are
+// needed from the result map, or ResultKnownOnly along with WithSkipPopulateStruct when the struct is only a schema.
+func WithResultContent(content ResultContent) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.resultContent = content
+	}
+}
+
+// WithNativeResultTypes is an UnmarshalOption function to set the nativeResultTypes option.
+// Native result types are disabled by default, meaning known fields are stored in the result map as their typed
+// Go values. Set this option to true to store them in their JSON map representation instead, the same way
+// MarshalToJSONMap does - as bool, string, float64, json.Number, []interface{} and map[string]interface{} values.
+// Numbers are represented according to the number mode, see WithNumberMode, the same way the values of unknown
+// fields are. This makes the result map hold JSON-native values only, and always be a valid input for
+// UnmarshalFromJSONMap.
+// Values that cannot be converted are reported as errors according to the unmarshalling mode, and stored as nil.
+func WithNativeResultTypes(nativeResultTypes bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.nativeResultTypes = nativeResultTypes
+	}
+}
+
 // WithSkipPopulateStruct is an UnmarshalOption function to set the skipPopulateStruct option.
 // Skipping populate struct is set to false by default.
 // If you do not intend to use the struct value once unmarshalling is finished, set this
@@ -44,11 +157,151 @@ func WithSkipPopulateStruct(skipPopulateStruct bool) UnmarshalOption {
 	}
 }
 
+// WithNestedResults is an UnmarshalOption function to set the nestedResults option.
+// Nested results are disabled by default, meaning nested objects are stored in the result map
+// as their typed struct values, and their unknown fields are dropped.
+// Set this option to true to have every nested object that is decoded into a struct, including
+// structs within slices, arrays and map values, stored in the result map as a map[string]interface{}
+// holding both its known (typed) fields and its unknown fields.
+func WithNestedResults(nestedResults bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.nestedResults = nestedResults
+	}
+}
+
+// WithCaseInsensitiveKeys is an UnmarshalOption function to set the caseInsensitiveKeys option.
+// Case-insensitive keys are disabled by default, meaning input keys must exactly match the struct fields.
+// Set this option to true to match input keys to struct fields the same way json.Unmarshal does, preferring an
+// exact match but accepting a case-insensitive one. Values are stored in the result map under their original input key.
+func WithCaseInsensitiveKeys(caseInsensitiveKeys bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.caseInsensitiveKeys = caseInsensitiveKeys
+	}
+}
+
+// WithNumberMode is an UnmarshalOption function to set the number mode.
+// The number mode affects numbers stored in the result map for fields that do not exist in the struct,
+// and numbers stored in interface{} struct fields, including the extras field.
+// When unmarshalling from a JSON map with NumberModeFloat64, such values are stored as given, meaning
+// json.Number inputs are kept as is. With other number modes, both float64 and json.Number inputs are converted.
+func WithNumberMode(numberMode NumberMode) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.numberMode = numberMode
+	}
+}
+
+// WithReplaceExisting is an UnmarshalOption function to set the replaceExisting option.
+// Replacing existing values is disabled by default, meaning values already held by the struct are decoded into
+// in place, the same way json.Unmarshal does - nested structs and maps are merged with the input, keeping fields
+// and entries missing from it, non-nil pointers are reused, and custom unmarshalers are called on the existing value.
+// Slices are always replaced. Set this option to true to decode every value into a newly allocated one instead.
+func WithReplaceExisting(replaceExisting bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.replaceExisting = replaceExisting
+	}
+}
+
+// WithOmitNullResults is an UnmarshalOption function to set the omitNullResults option.
+// Omitting null results is disabled by default, meaning known fields holding an explicit JSON null are stored
+// in the result map as nil entries, telling them apart from absent fields. Set this option to true to omit
+// them from the result map instead. Unknown fields holding null are always stored in the result map.
+// Either way, a JSON null sets pointer, map, slice and interface fields to nil, the same way json.Unmarshal does.
+func WithOmitNullResults(omitNullResults bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.omitNullResults = omitNullResults
+	}
+}
+
+// WithFieldSet is an UnmarshalOption function to set the fieldSet option.
+// Field sets are not recorded by default. Set this option to have every known struct field that was present
+// in the input, including fields of nested structs, recorded in the given FieldSet.
+func WithFieldSet(fieldSet *FieldSet) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.fieldSet = fieldSet
+	}
+}
+
+// WithArrayOverflowPolicy is an UnmarshalOption function to set the array overflow policy.
+func WithArrayOverflowPolicy(policy ArrayOverflowPolicy) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.arrayOverflowPolicy = policy
+	}
+}
+
+// WithDuplicateKeys is an UnmarshalOption function to set the duplicate key policy.
+// The policy applies to every JSON object decoded by Unmarshal, including nested objects and
+// the values of unknown fields. Known struct fields are identified by their JSON name, meaning keys
+// matched to the same field using WithCaseInsensitiveKeys are duplicates as well.
+// UnmarshalFromJSONMap is not affected, as its input map cannot hold duplicate keys.
+func WithDuplicateKeys(policy DuplicateKeyPolicy) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.duplicateKeys = policy
+	}
+}
+
+// WithInvalidUTF8 is an UnmarshalOption function to set the invalid UTF-8 policy.
+// The policy applies to every JSON string decoded by Unmarshal - object keys, including the keys stored in the
+// result map, values of known struct fields and values of unknown fields. UnmarshalFromJSONMap is not affected,
+// as its input holds already decoded strings.
+func WithInvalidUTF8(policy InvalidUTF8Policy) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.invalidUTF8 = policy
+	}
+}
+
+// WithRawUnknownFields is an UnmarshalOption function to set the rawUnknownFields option.
+// Raw unknown fields are disabled by default, meaning the values of fields that do not exist in the struct are decoded
+// into interface{} values. Set this option to true to have Unmarshal store them as json.RawMessage values instead,
+// in both the result map and the extras field, skipping their decoding entirely. Raw values are stored exactly
+// as they appear in the input, regardless of the number mode, the duplicate key policy and the invalid UTF-8 policy.
+// UnmarshalFromJSONMap is not affected, as its input holds already decoded values.
+func WithRawUnknownFields(rawUnknownFields bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.rawUnknownFields = rawUnknownFields
+	}
+}
+
+// WithLazyUnknownFields is an UnmarshalOption function to set the lazyUnknownFields option.
+// Lazy unknown fields are disabled by default. Set this option to true to have Unmarshal store the values of fields
+// that do not exist in the struct as *Lazy values, in both the result map and the extras field. A Lazy value holds
+// the raw value, and decodes it only when it is first accessed. This option takes precedence over WithRawUnknownFields.
+// UnmarshalFromJSONMap is not affected, as its input holds already decoded values.
+func WithLazyUnknownFields(lazyUnknownFields bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.lazyUnknownFields = lazyUnknownFields
+	}
+}
+
+// WithZeroCopyRawUnknownFields is an UnmarshalOption function to set the zeroCopyRawUnknownFields option.
+// It only applies along with WithRawUnknownFields or WithLazyUnknownFields. Zero-copy is disabled by default, meaning every raw value
+// is copied from the input data. Set this option to true to have raw values point directly into the input data,
+// saving their allocation. In that case, the input data must not be modified as long as the raw values are in use.
+func WithZeroCopyRawUnknownFields(zeroCopy bool) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.zeroCopyRawUnknownFields = zeroCopy
+	}
+}
+
 type UnmarshalOption func(*unmarshalOptions)
 
 type unmarshalOptions struct {
-	mode               Mode
-	skipPopulateStruct bool
+	mode                     Mode
+	skipPopulateStruct       bool
+	nestedResults            bool
+	caseInsensitiveKeys      bool
+	numberMode               NumberMode
+	replaceExisting          bool
+	omitNullResults          bool
+	fieldSet                 *FieldSet
+	arrayOverflowPolicy      ArrayOverflowPolicy
+	duplicateKeys            DuplicateKeyPolicy
+	invalidUTF8              InvalidUTF8Policy
+	rawUnknownFields         bool
+	lazyUnknownFields        bool
+	resultContent            ResultContent
+	nativeResultTypes        bool
+	orderedResults           bool
+	zeroCopyRawUnknownFields bool
 }
 
 func buildUnmarshalOptions(options []UnmarshalOption) *unmarshalOptions {
@@ -0,0 +1,134 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"encoding/json"
+	"github.com/mailru/easyjson/jwriter"
+)
+
+// OrderedMap is a JSON object that preserves the order of its keys. UnmarshalOrdered returns its result as an
+// *OrderedMap, holding the keys in the order they appear in the input, and stores nested JSON objects as *OrderedMap
+// values as well. OrderedMap values are accepted anywhere UnmarshalFromJSONMap expects a map[string]interface{}.
+// The zero value of OrderedMap is an empty map ready to use. OrderedMap is not safe for concurrent use.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// Len returns the number of entries in o.
+func (o *OrderedMap) Len() int {
+	return len(o.keys)
+}
+
+// Keys returns the keys of o, in order.
+func (o *OrderedMap) Keys() []string {
+	keys := make([]string, len(o.keys))
+	copy(keys, o.keys)
+	return keys
+}
+
+// Get returns the value stored in o for key. The ok result indicates whether key was found in o.
+func (o *OrderedMap) Get(key string) (value interface{}, ok bool) {
+	value, ok = o.values[key]
+	return value, ok
+}
+
+// Set stores value in o for key. New keys are added after all existing keys, while existing keys keep their position.
+func (o *OrderedMap) Set(key string, value interface{}) {
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Delete removes key from o, if it exists.
+func (o *OrderedMap) Delete(key string) {
+	if _, exists := o.values[key]; !exists {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Range calls f for each entry of o, in order. If f returns false, Range stops the iteration.
+// The entries of o must not be set or deleted during the iteration.
+func (o *OrderedMap) Range(f func(key string, value interface{}) bool) {
+	for _, key := range o.keys {
+		if !f(key, o.values[key]) {
+			return
+		}
+	}
+}
+
+// Map returns the entries of o as a map[string]interface{}, for example to pass them to UnmarshalFromJSONMap.
+// The returned map is a shallow copy, meaning nested *OrderedMap values are kept as is.
+func (o *OrderedMap) Map() map[string]interface{} {
+	result := make(map[string]interface{}, len(o.values))
+	for key, value := range o.values {
+		result[key] = value
+	}
+	return result
+}
+
+// MarshalJSON implements json.Marshaler, encoding the entries of o in order. Values are encoded using json.Marshal.
+func (o *OrderedMap) MarshalJSON() ([]byte, error) {
+	w := &jwriter.Writer{}
+	w.RawByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			w.RawByte(',')
+		}
+		w.String(key)
+		w.RawByte(':')
+		w.Raw(json.Marshal(o.values[key]))
+	}
+	w.RawByte('}')
+	return w.BuildBytes()
+}
+
+// orderedResult returns result as an *OrderedMap holding the given keys if keys is not nil, or as is otherwise.
+func orderedResult(result map[string]interface{}, keys *[]string) interface{} {
+	if keys == nil {
+		return result
+	}
+	return &OrderedMap{keys: *keys, values: result}
+}
+
+// setResult stores value in result for key. If keys is not nil, key is appended to it the first time it is stored.
+func setResult(result map[string]interface{}, keys *[]string, key string, value interface{}) {
+	if keys != nil {
+		if _, exists := result[key]; !exists {
+			*keys = append(*keys, key)
+		}
+	}
+	result[key] = value
+}
+
+// jsonMapObject returns the entries of the JSON map object v, which is either a map[string]interface{}
+// or an *OrderedMap. The ok result indicates whether v is a JSON map object.
+func jsonMapObject(v interface{}) (map[string]interface{}, bool) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return value, true
+	case *OrderedMap:
+		if value == nil {
+			return nil, false
+		}
+		if value.values == nil {
+			return map[string]interface{}{}, true
+		}
+		return value.values, true
+	}
+	return nil, false
+}
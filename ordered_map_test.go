@@ -0,0 +1,69 @@
+// Copyright 2022 PerimeterX. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package marshmallow
+
+import (
+	"github.com/go-test/deep"
+	"strings"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	t.Run("test_set_get_delete", func(t *testing.T) {
+		o := &OrderedMap{}
+		o.Set("b", 1)
+		o.Set("a", 2)
+		o.Set("c", 3)
+		o.Set("b", 4)
+		if diff := deep.Equal(o.Keys(), []string{"b", "a", "c"}); diff != nil {
+			t.Errorf("unexpected keys:\n%s", strings.Join(diff, "\n"))
+		}
+		if value, ok := o.Get("b"); !ok || value != 4 {
+			t.Errorf("unexpected value %v", value)
+		}
+		if _, ok := o.Get("d"); ok {
+			t.Errorf("unexpected value for missing key")
+		}
+		o.Delete("a")
+		o.Delete("d")
+		if diff := deep.Equal(o.Keys(), []string{"b", "c"}); diff != nil || o.Len() != 2 {
+			t.Errorf("unexpected keys:\n%s", strings.Join(diff, "\n"))
+		}
+		o.Set("a", 5)
+		if diff := deep.Equal(o.Map(), map[string]interface{}{"a": 5, "b": 4, "c": 3}); diff != nil {
+			t.Errorf("unexpected map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_range", func(t *testing.T) {
+		o := &OrderedMap{}
+		o.Set("b", 1)
+		o.Set("a", 2)
+		o.Set("c", 3)
+		var keys []string
+		o.Range(func(key string, value interface{}) bool {
+			keys = append(keys, key)
+			return key != "a"
+		})
+		if diff := deep.Equal(keys, []string{"b", "a"}); diff != nil {
+			t.Errorf("unexpected keys:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_marshal_json", func(t *testing.T) {
+		nested := &OrderedMap{}
+		nested.Set("z", []interface{}{1, "x"})
+		nested.Set("y", nil)
+		o := &OrderedMap{}
+		o.Set("b", nested)
+		o.Set("a", "\"quoted\"")
+		data, err := o.MarshalJSON()
+		if err != nil || string(data) != `{"b":{"z":[1,"x"],"y":null},"a":"\"quoted\""}` {
+			t.Errorf("unexpected JSON %s, error %v", data, err)
+		}
+		empty, err := (&OrderedMap{}).MarshalJSON()
+		if err != nil || string(empty) != `{}` {
+			t.Errorf("unexpected JSON %s, error %v", empty, err)
+		}
+	})
+}
@@ -5,59 +5,487 @@
 package marshmallow
 
 import (
+	"encoding"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
-var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+var (
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
 
 type reflectionInfo struct {
-	path []int
-	t    reflect.Type
+	name      string
+	path      []int
+	t         reflect.Type
+	omitEmpty bool
+	quoted    bool
 }
 
+// field returns the field of target described by r. Nil pointers to embedded structs along the path
+// are allocated. If such a pointer cannot be allocated, since it is an unexported field, an invalid
+// reflect.Value is returned.
 func (r reflectionInfo) field(target reflect.Value) reflect.Value {
 	current := target
 	for _, i := range r.path {
+		if current.Kind() == reflect.Ptr {
+			if current.IsNil() {
+				if !current.CanSet() {
+					return reflect.Value{}
+				}
+				current.Set(reflect.New(current.Type().Elem()))
+			}
+			current = current.Elem()
+		}
 		current = current.Field(i)
 	}
 	return current
 }
 
-func mapStructFields(target interface{}) map[string]reflectionInfo {
-	t := reflectStructType(target)
+// existingField returns the field of target described by r, without allocating nil pointers to
+// embedded structs along the path. If such a nil pointer is encountered, false is returned.
+func (r reflectionInfo) existingField(target reflect.Value) (reflect.Value, bool) {
+	current := target
+	for _, i := range r.path {
+		if current.Kind() == reflect.Ptr {
+			if current.IsNil() {
+				return reflect.Value{}, false
+			}
+			current = current.Elem()
+		}
+		current = current.Field(i)
+	}
+	return current, true
+}
+
+// structInfo holds the reflection information of a struct type.
+// fields maps JSON keys to their struct fields, names holds the same keys in the order
+// of the struct fields, foldedFields maps the case folded form of the keys to their struct fields,
+// and extras, if set, points to the catch-all field receiving all keys that are not mapped to any other field.
+type structInfo struct {
+	fields       map[string]reflectionInfo
+	names        []string
+	foldedFields map[string]reflectionInfo
+	extras       *reflectionInfo
+}
+
+// lookup returns the struct field matching the given key. If caseInsensitive is set and there is no
+// exact match, the first field, in struct order, matching the key case-insensitively is returned.
+func (s *structInfo) lookup(key string, caseInsensitive bool) (reflectionInfo, bool) {
+	refInfo, exists := s.fields[key]
+	if exists || !caseInsensitive {
+		return refInfo, exists
+	}
+	var buf [64]byte
+	refInfo, exists = s.foldedFields[string(foldName(buf[:0], key))]
+	return refInfo, exists
+}
+
+// extrasMap returns the extras map of the given struct value, allocating it if needed.
+// If the extras field cannot be reached, an invalid reflect.Value is returned.
+func (s *structInfo) extrasMap(structValue reflect.Value) reflect.Value {
+	field := s.extras.field(structValue)
+	if field.IsValid() && field.IsNil() {
+		field.Set(reflect.MakeMap(field.Type()))
+	}
+	return field
+}
+
+func mapStructFields(target interface{}) *structInfo {
+	return mapStructTypeFields(reflectStructType(target))
+}
+
+func mapStructTypeFields(t reflect.Type) *structInfo {
 	result := cacheLookup(t)
 	if result != nil {
 		return result
 	}
-	result = make(map[string]reflectionInfo, t.NumField())
-	mapTypeFields(t, result, nil)
+	result = mapTypeFields(t)
 	cacheStore(t, result)
 	return result
 }
 
-func mapTypeFields(t reflect.Type, result map[string]reflectionInfo, path []int) {
-	num := t.NumField()
-	for i := 0; i < num; i++ {
-		field := t.Field(i)
-		fieldPath := append(path, i)
-		if field.Anonymous && field.Type.Kind() == reflect.Struct {
-			mapTypeFields(field.Type, result, fieldPath)
+// typeField is a candidate field found while mapping the fields of a struct type.
+type typeField struct {
+	name   string
+	tagged bool
+	info   reflectionInfo
+}
+
+// mapTypeFields maps the fields of t following the rules of json.Unmarshal. Fields of embedded
+// structs, or pointers to structs, are promoted as if they were fields of t, unless they are given a name
+// with a tag. When several fields share the same name, the shallowest one is chosen, preferring a tagged one.
+// If there is no single such field, the name is ambiguous and all of its fields are ignored.
+func mapTypeFields(t reflect.Type) *structInfo {
+	var candidates []typeField
+	var extras []reflectionInfo
+	type scan struct {
+		t    reflect.Type
+		path []int
+	}
+	var current []scan
+	next := []scan{{t: t}}
+	var count, nextCount map[reflect.Type]int
+	visited := make(map[reflect.Type]bool)
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, make(map[reflect.Type]int)
+		for _, s := range current {
+			if visited[s.t] {
+				continue
+			}
+			visited[s.t] = true
+			for i := 0; i < s.t.NumField(); i++ {
+				field := s.t.Field(i)
+				fieldType := field.Type
+				if fieldType.Name() == "" && fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if field.Anonymous {
+					if field.PkgPath != "" && fieldType.Kind() != reflect.Struct {
+						continue
+					}
+				} else if field.PkgPath != "" {
+					continue
+				}
+				path := make([]int, len(s.path)+1)
+				copy(path, s.path)
+				path[len(s.path)] = i
+				if isExtrasField(field) {
+					extras = append(extras, reflectionInfo{path: path, t: field.Type})
+					continue
+				}
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options := parseTag(tag)
+				if name != "" || !field.Anonymous || fieldType.Kind() != reflect.Struct {
+					candidate := typeField{
+						name:   name,
+						tagged: name != "",
+						info: reflectionInfo{
+							path:      path,
+							t:         field.Type,
+							omitEmpty: hasTagOption(options, "omitempty"),
+							quoted:    hasTagOption(options, "string") && isQuotable(field.Type),
+						},
+					}
+					if candidate.name == "" {
+						candidate.name = field.Name
+					}
+					candidates = append(candidates, candidate)
+					if count[s.t] > 1 {
+						// the embedding struct appears more than once at this depth, thus all its fields are
+						// ambiguous. Adding a duplicate candidate makes sure the name is ignored.
+						candidates = append(candidates, candidate)
+					}
+					continue
+				}
+				nextCount[fieldType]++
+				if nextCount[fieldType] == 1 {
+					next = append(next, scan{t: fieldType, path: path})
+				}
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if len(a.info.path) != len(b.info.path) {
+			return len(a.info.path) < len(b.info.path)
+		}
+		return a.tagged && !b.tagged
+	})
+	var dominant []typeField
+	for i := 0; i < len(candidates); {
+		j := i + 1
+		for j < len(candidates) && candidates[j].name == candidates[i].name {
+			j++
+		}
+		if j-i == 1 || len(candidates[i].info.path) < len(candidates[i+1].info.path) ||
+			candidates[i].tagged != candidates[i+1].tagged {
+			dominant = append(dominant, candidates[i])
+		}
+		i = j
+	}
+	sort.Slice(dominant, func(i, j int) bool {
+		return comparePaths(dominant[i].info.path, dominant[j].info.path) < 0
+	})
+	result := &structInfo{
+		fields:       make(map[string]reflectionInfo, len(dominant)),
+		names:        make([]string, len(dominant)),
+		foldedFields: make(map[string]reflectionInfo, len(dominant)),
+	}
+	for i, field := range dominant {
+		field.info.name = field.name
+		result.fields[field.name] = field.info
+		result.names[i] = field.name
+		folded := string(foldName(nil, field.name))
+		if _, exists := result.foldedFields[folded]; !exists {
+			result.foldedFields[folded] = field.info
+		}
+	}
+	if len(extras) > 0 {
+		result.extras = &extras[0]
+	}
+	return result
+}
+
+func comparePaths(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
+
+// isExtrasField reports whether the given field is tagged with the marshmallow extras option,
+// e.g. `marshmallow:",extras"`, and is of a map type able to hold any JSON key and value.
+func isExtrasField(field reflect.StructField) bool {
+	_, options := parseTag(field.Tag.Get("marshmallow"))
+	if !hasTagOption(options, "extras") {
+		return false
+	}
+	t := field.Type
+	return field.PkgPath == "" && t.Kind() == reflect.Map && t.Key().Kind() == reflect.String &&
+		t.Elem().Kind() == reflect.Interface && t.Elem().NumMethod() == 0
+}
+
+// isQuotable reports whether the string tag option applies to fields of type t. Following json.Unmarshal,
+// it only applies to fields of string, floating point, integer, or boolean types, or unnamed pointers to them.
+func isQuotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr && t.Name() == "" {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32,
+		reflect.Float64, reflect.String:
+		return true
+	}
+	return false
+}
+
+// convertQuoted converts the content of a JSON string, holding a value of a field tagged with the
+// string option, to a value of type t.
+func convertQuoted(s string, t reflect.Type) (interface{}, bool) {
+	if s == "null" {
+		return nil, true
+	}
+	elemType := t
+	if t.Kind() == reflect.Ptr {
+		elemType = t.Elem()
+	}
+	var value interface{}
+	var err error
+	switch elemType.Kind() {
+	case reflect.Bool:
+		switch s {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		default:
+			return nil, false
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err = strconv.ParseInt(s, 10, elemType.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value, err = strconv.ParseUint(s, 10, elemType.Bits())
+	case reflect.Float32, reflect.Float64:
+		if !isValidNumber(s) {
+			return nil, false
+		}
+		value, err = strconv.ParseFloat(s, elemType.Bits())
+	case reflect.String:
+		var str string
+		err = json.Unmarshal([]byte(s), &str)
+		value = str
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return nil, false
+	}
+	converted := reflect.ValueOf(value).Convert(elemType)
+	if t.Kind() == reflect.Ptr {
+		ptr := reflect.New(elemType)
+		ptr.Elem().Set(converted)
+		return ptr.Interface(), true
+	}
+	return converted.Interface(), true
+}
+
+// isValidNumber reports whether s is a valid JSON number literal.
+func isValidNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s[0] == '-' {
+		s = s[1:]
+		if s == "" {
+			return false
+		}
+	}
+	switch {
+	case s[0] == '0':
+		s = s[1:]
+	case '1' <= s[0] && s[0] <= '9':
+		s = s[1:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	default:
+		return false
+	}
+	if len(s) >= 2 && s[0] == '.' && '0' <= s[1] && s[1] <= '9' {
+		s = s[2:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+	if len(s) >= 2 && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s[0] == '+' || s[0] == '-' {
+			s = s[1:]
+			if s == "" {
+				return false
+			}
+		}
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+	return s == ""
+}
+
+// replaceInvalidUTF8 returns s with every byte that is not part of a valid UTF-8 sequence
+// replaced by U+FFFD, the same way json.Unmarshal does.
+func replaceInvalidUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteRune(utf8.RuneError)
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// hasLoneSurrogate reports whether the raw JSON string literal raw holds a \uXXXX escaped UTF-16
+// surrogate that is not part of a valid surrogate pair.
+func hasLoneSurrogate(raw []byte) bool {
+	for i := 0; i < len(raw)-1; i++ {
+		if raw[i] != '\\' {
+			continue
+		}
+		i++
+		if raw[i] != 'u' {
 			continue
 		}
-		name := field.Tag.Get("json")
-		if name == "" || name == "-" {
+		r := decodeHex4(raw[i+1:])
+		if !utf16.IsSurrogate(r) {
 			continue
 		}
-		if index := strings.Index(name, ","); index > -1 {
-			name = name[:index]
+		if len(raw) > i+6 && raw[i+5] == '\\' && raw[i+6] == 'u' &&
+			utf16.DecodeRune(r, decodeHex4(raw[i+7:])) != utf8.RuneError {
+			i += 6
+			continue
 		}
-		result[name] = reflectionInfo{
-			path: fieldPath,
-			t:    field.Type,
+		return true
+	}
+	return false
+}
+
+// decodeHex4 decodes the 4 hexadecimal digits at the beginning of b, returning -1 if they are invalid.
+func decodeHex4(b []byte) rune {
+	if len(b) < 4 {
+		return -1
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return -1
 		}
+		r = r<<4 | rune(c)
 	}
+	return r
+}
+
+// foldName appends the case folded form of name to buf and returns the extended buffer.
+// Names that are equal under Unicode case folding, as used by json.Unmarshal to match keys,
+// have the same folded form. Each rune is replaced by the smallest rune of its folding orbit.
+func foldName(buf []byte, name string) []byte {
+	for i := 0; i < len(name); {
+		c := name[i]
+		if c < utf8.RuneSelf {
+			if 'a' <= c && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			buf = append(buf, c)
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(name[i:])
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < folded {
+				folded = f
+			}
+		}
+		var encoded [utf8.UTFMax]byte
+		n := utf8.EncodeRune(encoded[:], folded)
+		buf = append(buf, encoded[:n]...)
+		i += size
+	}
+	return buf
+}
+
+// parseTag splits a struct tag into its name and its comma separated options.
+func parseTag(tag string) (string, string) {
+	if index := strings.Index(tag, ","); index > -1 {
+		return tag[:index], tag[index+1:]
+	}
+	return tag, ""
+}
+
+func hasTagOption(options string, option string) bool {
+	for options != "" {
+		var current string
+		current, options = parseTag(options)
+		if current == option {
+			return true
+		}
+	}
+	return false
 }
 
 func reflectStructValue(target interface{}) reflect.Value {
@@ -81,101 +509,215 @@ var primitiveConverters = map[reflect.Kind]func(v interface{}) (interface{}, boo
 		res, ok := v.(bool)
 		return res, ok
 	},
-	reflect.Int: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return int(res), true
-		}
-		return v, false
-	},
-	reflect.Int8: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return int8(res), true
-		}
-		return v, false
-	},
-	reflect.Int16: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return int16(res), true
-		}
-		return v, false
-	},
-	reflect.Int32: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return int32(res), true
-		}
-		return v, false
+	reflect.String: func(v interface{}) (interface{}, bool) {
+		res, ok := v.(string)
+		return res, ok
 	},
-	reflect.Int64: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return int64(res), true
+}
+
+// isNumberKind reports whether values of the given kind are decoded from JSON numbers.
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// parseNumber converts the JSON number text s into a value of the numeric kind of t. Integers are parsed
+// directly, without a float64 detour. Numbers holding a fraction or an exponent are accepted by integer kinds
+// only if they are integral. Numbers that do not fit in t are rejected.
+func parseNumber(s string, t reflect.Type) (interface{}, error) {
+	kind := t.Kind()
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err == nil {
+			return intOfKind(n, kind), nil
 		}
-		return v, false
-	},
-	reflect.Uint: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return uint(res), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err == nil {
+			return uintOfKind(n, kind), nil
 		}
-		return v, false
-	},
-	reflect.Uint8: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return uint8(res), true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, newNumberOverflowError(s, t)
 		}
-		return v, false
-	},
-	reflect.Uint16: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return uint16(res), true
+		return nil, newInvalidNumberError(s)
+	}
+	return convertFloat(f, t)
+}
+
+// convertFloat converts f into a value of the numeric kind of t.
+// Values that are not integral are rejected by integer kinds, and values that do not fit in t are rejected.
+func convertFloat(f float64, t reflect.Type) (interface{}, error) {
+	kind := t.Kind()
+	switch kind {
+	case reflect.Float32:
+		if math.Abs(f) > math.MaxFloat32 {
+			return nil, newNumberOverflowError(formatFloat(f), t)
 		}
-		return v, false
-	},
-	reflect.Uint32: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return uint32(res), true
+		return float32(f), nil
+	case reflect.Float64:
+		return f, nil
+	}
+	if f != math.Trunc(f) {
+		return nil, newNumberFractionError(formatFloat(f), t)
+	}
+	bits := t.Bits()
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		limit := math.Ldexp(1, bits-1)
+		if f < -limit || f >= limit {
+			return nil, newNumberOverflowError(formatFloat(f), t)
 		}
-		return v, false
-	},
-	reflect.Uint64: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return uint64(res), true
+		return intOfKind(int64(f), kind), nil
+	}
+	if f < 0 || f >= math.Ldexp(1, bits) {
+		return nil, newNumberOverflowError(formatFloat(f), t)
+	}
+	return uintOfKind(uint64(f), kind), nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func intOfKind(n int64, kind reflect.Kind) interface{} {
+	switch kind {
+	case reflect.Int:
+		return int(n)
+	case reflect.Int8:
+		return int8(n)
+	case reflect.Int16:
+		return int16(n)
+	case reflect.Int32:
+		return int32(n)
+	}
+	return n
+}
+
+func uintOfKind(n uint64, kind reflect.Kind) interface{} {
+	switch kind {
+	case reflect.Uint:
+		return uint(n)
+	case reflect.Uint8:
+		return uint8(n)
+	case reflect.Uint16:
+		return uint16(n)
+	case reflect.Uint32:
+		return uint32(n)
+	case reflect.Uintptr:
+		return uintptr(n)
+	}
+	return n
+}
+
+// convertNumber returns the representation of the JSON number text s according to the given number mode.
+func convertNumber(s string, mode NumberMode) (interface{}, error) {
+	switch mode {
+	case NumberModeJSONNumber:
+		return json.Number(s), nil
+	case NumberModeInt64IfIntegral:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
 		}
-		return v, false
-	},
-	reflect.Float32: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return float32(res), true
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return f, err
 		}
-		return v, false
-	},
-	reflect.Float64: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(float64)
-		if ok {
-			return res, true
+		if n, ok := integralInt64(f); ok {
+			return n, nil
 		}
-		return v, false
-	},
-	reflect.Interface: func(v interface{}) (interface{}, bool) {
-		return v, true
-	},
-	reflect.String: func(v interface{}) (interface{}, bool) {
-		res, ok := v.(string)
-		return res, ok
-	},
+		return f, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// integralInt64 returns f as an int64 if it is integral and within the int64 range.
+func integralInt64(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// existingValue returns the current value of the field described by r within structValue, to be merged into
+// while decoding. An invalid reflect.Value is returned if the field cannot be reached without allocating
+// an embedded pointer, or cannot be set.
+func existingValue(r reflectionInfo, structValue reflect.Value) reflect.Value {
+	field, exists := r.existingField(structValue)
+	if !exists || !field.CanSet() {
+		return reflect.Value{}
+	}
+	return field
+}
+
+// isByteSlice reports whether values of type t are decoded from base64 encoded JSON strings, the same
+// way json.Unmarshal does. JSON arrays of numbers are decoded into such values as well.
+func isByteSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// decodeBase64 decodes the base64 encoded string s into a byte slice of type t.
+func decodeBase64(s string, t reflect.Type) (interface{}, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	value := reflect.New(t).Elem()
+	value.SetBytes(b)
+	return value.Interface(), nil
+}
+
+// isNullable reports whether fields of type t are set to nil by a JSON null. Following json.Unmarshal,
+// these are pointers, maps, slices and interfaces, while fields of other types are left unchanged.
+func isNullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// setNull sets the field described by r within structValue to nil. Nil pointers to embedded structs
+// along the path are not allocated, as the field is already nil.
+func setNull(r reflectionInfo, structValue reflect.Value) {
+	field := existingValue(r, structValue)
+	if field.IsValid() {
+		field.Set(reflect.Zero(r.t))
+	}
+}
+
+// isMergeableUnmarshaler reports whether the custom unmarshaler of the existing value should be called in place,
+// the same way json.Unmarshal does - either on a non-nil pointer, or on the address of a non-pointer value.
+func isMergeableUnmarshaler(existing reflect.Value, unmarshaler reflect.Type) bool {
+	t := existing.Type()
+	if t.Kind() == reflect.Ptr {
+		return !existing.IsNil() && t.Implements(unmarshaler)
+	}
+	return t.Kind() != reflect.Interface && existing.CanAddr() && reflect.PtrTo(t).Implements(unmarshaler)
+}
+
+// pointerTo returns a pointer to value. If existing is a non-nil pointer whose element can hold value,
+// value is stored in it and existing is returned, keeping the pointer identity like json.Unmarshal does.
+func pointerTo(value interface{}, existing reflect.Value) reflect.Value {
+	reflectValue := reflect.ValueOf(value)
+	if existing.IsValid() && !existing.IsNil() && reflectValue.Type().AssignableTo(existing.Type().Elem()) {
+		existing.Elem().Set(reflectValue)
+		return existing
+	}
+	result := reflect.New(reflectValue.Type())
+	result.Elem().Set(reflectValue)
+	return result
 }
 
 func assignValue(field reflect.Value, value interface{}) {
-	if value == nil {
+	if value == nil || !field.IsValid() {
 		return
 	}
 	reflectValue := reflect.ValueOf(value)
@@ -184,6 +726,89 @@ func assignValue(field reflect.Value, value interface{}) {
 	}
 }
 
+// containsStruct reports whether values of type t hold structs that are decoded field by field,
+// either directly or as the elements of pointers, slices, arrays and maps. Types implementing
+// one of the given custom unmarshaler interfaces are decoded as a whole and are not considered.
+func containsStruct(t reflect.Type, unmarshalers ...reflect.Type) bool {
+	var visited []reflect.Type
+	for {
+		for _, unmarshaler := range unmarshalers {
+			if t.Implements(unmarshaler) || reflect.PtrTo(t).Implements(unmarshaler) {
+				return false
+			}
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			return true
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			for _, v := range visited {
+				if v == t {
+					return false
+				}
+			}
+			visited = append(visited, t)
+			t = t.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+// isTextUnmarshaler reports whether values of type t are decoded from JSON strings using
+// encoding.TextUnmarshaler, either directly or through a pointer receiver.
+func isTextUnmarshaler(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// unmarshalText returns a value of type t decoded from text using its encoding.TextUnmarshaler
+// implementation. Pointer types are allocated, following json.Unmarshal.
+func unmarshalText(t reflect.Type, text string) (interface{}, error) {
+	if t.Kind() == reflect.Ptr && t.Implements(textUnmarshalerType) {
+		value := reflect.New(t.Elem())
+		err := value.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+		return value.Interface(), err
+	}
+	value := reflect.New(t)
+	err := value.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	return value.Elem().Interface(), err
+}
+
+// isMapKeyKind reports whether map keys of the given kind can be decoded from object keys
+// without a custom unmarshaler, following json.Unmarshal.
+func isMapKeyKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// convertMapKey converts an object key into a value of the map key type t. String kinds are converted
+// as is, while integer kinds are parsed in base 10. It returns false if key is not a valid value of t.
+func convertMapKey(key string, t reflect.Type) (interface{}, bool) {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(key).Convert(t).Interface(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return nil, false
+		}
+		return reflect.ValueOf(n).Convert(t).Interface(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return nil, false
+		}
+		return reflect.ValueOf(n).Convert(t).Interface(), true
+	}
+	return nil, false
+}
+
 func isValidValue(v interface{}) bool {
 	value := reflect.ValueOf(v)
 	return value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Struct && !value.IsNil()
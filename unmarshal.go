@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"github.com/mailru/easyjson/jlexer"
 	"reflect"
+	"strings"
+	"unicode/utf8"
 )
 
 // Unmarshal parses the JSON-encoded object in data and stores the values
@@ -22,13 +24,34 @@ import (
 // by returning ErrInvalidInput.
 // - Unmarshal only operates on struct values. It will reject all other types of v by
 // returning ErrInvalidValue.
+// - Fields that do not exist in a struct are also stored in its extras field, if it has one. An extras
+// field is a map[string]interface{} field tagged with `marshmallow:",extras"`. This applies to all structs,
+// including nested ones.
 // - Unmarshal supports three types of Mode values. Each mode is self documented and affects
 // how Unmarshal behaves.
 func Unmarshal(data []byte, v interface{}, options ...UnmarshalOption) (map[string]interface{}, error) {
+	return unmarshal(data, v, buildUnmarshalOptions(options), nil)
+}
+
+// UnmarshalOrdered is the same as Unmarshal, except that it returns the result as an *OrderedMap, preserving
+// the order of the input keys. JSON objects stored in the result, including the values of unknown fields,
+// the results of nested structs with WithNestedResults, and values decoded by *Lazy, are stored as
+// *OrderedMap values as well. Typed Go maps, such as map[string]interface{} struct fields, are not affected.
+func UnmarshalOrdered(data []byte, v interface{}, options ...UnmarshalOption) (*OrderedMap, error) {
+	opts := buildUnmarshalOptions(options)
+	opts.orderedResults = true
+	var keys []string
+	result, err := unmarshal(data, v, opts, &keys)
+	if result == nil {
+		return nil, err
+	}
+	return &OrderedMap{keys: keys, values: result}, err
+}
+
+func unmarshal(data []byte, v interface{}, opts *unmarshalOptions, keys *[]string) (map[string]interface{}, error) {
 	if !isValidValue(v) {
 		return nil, ErrInvalidValue
 	}
-	opts := buildUnmarshalOptions(options)
 	useMultipleErrors := opts.mode == ModeAllowMultipleErrors || opts.mode == ModeFailOverToOriginalValue
 	d := &decoder{options: opts, lexer: &jlexer.Lexer{Data: data, UseMultipleErrors: useMultipleErrors}}
 	result := make(map[string]interface{})
@@ -37,7 +60,7 @@ func Unmarshal(data []byte, v interface{}, options ...UnmarshalOption) (map[stri
 	} else if !d.lexer.IsDelim('{') {
 		return nil, ErrInvalidInput
 	} else {
-		d.populateStruct(v, result)
+		d.populateStruct(v, result, keys)
 	}
 	d.lexer.Consumed()
 	if useMultipleErrors {
@@ -57,55 +80,114 @@ func Unmarshal(data []byte, v interface{}, options ...UnmarshalOption) (map[stri
 type decoder struct {
 	options *unmarshalOptions
 	lexer   *jlexer.Lexer
+	path    []string
 }
 
-func (d *decoder) populateStruct(structInstance interface{}, result map[string]interface{}) (interface{}, bool) {
+// populateStruct decodes a JSON object into the struct pointed to by structInstance, storing its fields in result
+// if it is not nil. If keys is not nil, the keys stored in result are appended to it in order.
+func (d *decoder) populateStruct(structInstance interface{}, result map[string]interface{}, keys *[]string) (interface{}, bool) {
 	doPopulate := !d.options.skipPopulateStruct || result == nil
 	var structValue reflect.Value
 	if doPopulate {
 		structValue = reflectStructValue(structInstance)
 	}
-	fields := mapStructFields(structInstance)
+	info := mapStructFields(structInstance)
 	var clone map[string]interface{}
+	var cloneKeys *[]string
 	if d.options.mode == ModeFailOverToOriginalValue {
-		clone = make(map[string]interface{}, len(fields))
+		clone = make(map[string]interface{}, len(info.fields))
+		cloneKeys = d.newResultKeys()
 	}
+	knownResult := result != nil && d.options.resultContent != ResultUnknownOnly
+	unknownResult := result != nil && d.options.resultContent != ResultKnownOnly
+	var extras reflect.Value
+	seen := d.newSeenKeys()
 	d.lexer.Delim('{')
 	for !d.lexer.IsDelim('}') {
-		key := d.lexer.UnsafeFieldName(false)
+		key := d.fieldName()
 		d.lexer.WantColon()
-		refInfo, exists := fields[key]
+		refInfo, exists := info.lookup(key, d.options.caseInsensitiveKeys)
+		if seen != nil {
+			seenKey := key
+			if exists {
+				seenKey = refInfo.name
+			}
+			if d.skipDuplicateKey(seen, seenKey) {
+				d.lexer.SkipRecursive()
+				d.lexer.WantComma()
+				continue
+			}
+		}
 		if exists {
-			value, isValidType := d.valueByReflectType(refInfo.t, false)
+			var value, resultValue interface{}
+			var isValidType bool
+			isNull := d.lexer.IsNull()
+			if d.options.fieldSet != nil {
+				d.path = append(d.path, refInfo.name)
+				d.options.fieldSet.add(d.path, isNull)
+			}
+			if isNull && isNullable(refInfo.t) {
+				d.lexer.Skip()
+				if doPopulate {
+					setNull(refInfo, structValue)
+				}
+				isValidType = true
+			} else if refInfo.quoted {
+				value, resultValue, isValidType = d.quotedValueByReflectType(refInfo.t)
+			} else {
+				var existing reflect.Value
+				if doPopulate && !d.options.replaceExisting {
+					existing = existingValue(refInfo, structValue)
+				}
+				value, resultValue, isValidType = d.valueByReflectType(refInfo.t, false, existing)
+			}
+			if d.options.fieldSet != nil {
+				d.path = d.path[:len(d.path)-1]
+			}
 			if isValidType {
 				if value != nil && doPopulate {
 					field := refInfo.field(structValue)
 					assignValue(field, value)
 				}
-				if result != nil {
-					result[key] = value
-				} else if clone != nil {
-					clone[key] = value
+				if !isNull || !d.options.omitNullResults {
+					if knownResult {
+						setResult(result, keys, key, d.nativeResultValue(resultValue))
+					} else if result == nil && clone != nil {
+						setResult(clone, cloneKeys, key, d.nativeResultValue(resultValue))
+					}
 				}
 			} else {
 				switch d.options.mode {
 				case ModeFailOnFirstError:
 					return nil, false
 				case ModeFailOverToOriginalValue:
-					if result != nil {
-						result[key] = value
-					} else {
-						clone[key] = value
+					if knownResult {
+						setResult(result, keys, key, value)
+					} else if result == nil {
+						setResult(clone, cloneKeys, key, value)
 						d.lexer.WantComma()
-						d.drainLexerMap(clone)
-						return clone, false
+						d.drainLexerMap(clone, cloneKeys, seen)
+						return orderedResult(clone, cloneKeys), false
 					}
 				}
 			}
-		} else {
-			if result != nil {
-				result[key] = d.lexer.Interface()
+		} else if unknownResult || (result == nil && clone != nil) || (info.extras != nil && doPopulate) {
+			value := d.unknownValue()
+			if unknownResult {
+				setResult(result, keys, key, value)
+			} else if result == nil && clone != nil {
+				setResult(clone, cloneKeys, key, value)
+			}
+			if info.extras != nil && doPopulate {
+				if !extras.IsValid() {
+					extras = info.extrasMap(structValue)
+				}
+				if extras.IsValid() {
+					extras.SetMapIndex(reflect.ValueOf(d.extrasKey(key)), safeReflectValue(info.extras.t.Elem(), value))
+				}
 			}
+		} else {
+			d.lexer.SkipRecursive()
 		}
 		d.lexer.WantComma()
 	}
@@ -113,29 +195,68 @@ func (d *decoder) populateStruct(structInstance interface{}, result map[string]i
 	return structInstance, true
 }
 
-func (d *decoder) valueByReflectType(t reflect.Type, isPtr bool) (interface{}, bool) {
+// nativeResultValue returns the value stored in the result map for a known field. With the nativeResultTypes
+// option, v is converted into its JSON map representation.
+func (d *decoder) nativeResultValue(v interface{}) interface{} {
+	if _, ok := v.(*OrderedMap); ok || !d.options.nativeResultTypes {
+		return v
+	}
+	native, err := jsonMapValue(v, d.options.numberMode)
+	if err != nil {
+		addResultValueLexerError(d.lexer, err)
+		return nil
+	}
+	return native
+}
+
+// valueByReflectType decodes the next JSON value into a value of type t. If existing is valid, it holds the
+// current value of the target, and the JSON value is merged into it in place where json.Unmarshal would do so.
+func (d *decoder) valueByReflectType(t reflect.Type, isPtr bool, existing reflect.Value) (interface{}, interface{}, bool) {
+	if isNumberUnmarshaler(t) {
+		return d.valueFromNumberUnmarshaler(t)
+	}
+	if existing.IsValid() && isMergeableUnmarshaler(existing, unmarshalerType) {
+		target := existing
+		if t.Kind() != reflect.Ptr {
+			target = existing.Addr()
+		}
+		d.valueFromCustomUnmarshaler(target.Interface().(json.Unmarshaler))
+		result := existing.Interface()
+		return result, result, true
+	}
 	if t.Implements(unmarshalerType) {
 		result := reflect.New(t.Elem()).Interface()
 		d.valueFromCustomUnmarshaler(result.(json.Unmarshaler))
-		return result, true
+		return result, result, true
 	}
 	if reflect.PtrTo(t).Implements(unmarshalerType) {
 		value := reflect.New(t)
 		d.valueFromCustomUnmarshaler(value.Interface().(json.Unmarshaler))
-		return value.Elem().Interface(), true
+		result := value.Elem().Interface()
+		return result, result, true
+	}
+	if isTextUnmarshaler(t) {
+		return d.valueFromTextUnmarshaler(t)
 	}
 	kind := t.Kind()
+	if kind == reflect.Interface {
+		v := d.interfaceValue()
+		return v, v, true
+	}
+	if isNumberKind(kind) {
+		return d.numberByReflectType(t)
+	}
 	if converter := primitiveConverters[kind]; converter != nil {
-		v := d.lexer.Interface()
+		v := d.interfaceValue()
 		if v == nil {
-			return nil, true
+			return nil, nil, true
 		}
 		converted, ok := converter(v)
 		if !ok {
 			addUnexpectedTypeLexerError(d.lexer, t)
-			return v, false
+			return v, v, false
 		}
-		return converted, true
+		return converted, converted, true
 	}
 	switch kind {
 	case reflect.Slice:
@@ -143,167 +264,359 @@ func (d *decoder) valueByReflectType(t reflect.Type, isPtr bool) (interface{}, b
 	case reflect.Array:
 		return d.buildArray(t)
 	case reflect.Map:
-		return d.buildMap(t)
+		return d.buildMap(t, existing)
 	case reflect.Struct:
-		value, valid := d.buildStruct(t)
+		value, resultValue, valid := d.buildStruct(t, existing)
 		if value == nil {
-			return nil, valid
+			return nil, nil, valid
 		}
 		if !valid {
-			return value, false
+			return value, value, false
+		}
+		value = reflect.ValueOf(value).Elem().Interface()
+		if !d.options.nestedResults {
+			resultValue = value
 		}
-		return reflect.ValueOf(value).Elem().Interface(), valid
+		return value, resultValue, valid
 	case reflect.Ptr:
+		var elem reflect.Value
+		if existing.IsValid() && !existing.IsNil() {
+			elem = existing.Elem()
+		}
 		if t.Elem().Kind() == reflect.Struct {
-			return d.buildStruct(t.Elem())
+			return d.buildStruct(t.Elem(), elem)
 		}
-		value, valid := d.valueByReflectType(t.Elem(), true)
+		value, resultValue, valid := d.valueByReflectType(t.Elem(), true, elem)
 		if value == nil {
-			return nil, valid
+			return nil, nil, valid
 		}
 		if !valid {
-			return value, false
+			return value, value, false
+		}
+		result := pointerTo(value, existing)
+		if !d.isNestedResult(t.Elem()) {
+			resultValue = result.Interface()
 		}
-		result := reflect.New(reflect.TypeOf(value))
-		result.Elem().Set(reflect.ValueOf(value))
-		return result.Interface(), valid
+		return result.Interface(), resultValue, valid
 	}
 	addUnsupportedTypeLexerError(d.lexer, t)
-	return nil, false
+	return nil, nil, false
 }
 
-func (d *decoder) buildSlice(sliceType reflect.Type) (interface{}, bool) {
+// numberByReflectType decodes a JSON number into a value of the numeric kind of t, parsing its text directly.
+// Numbers that do not fit in t, or are not integral while t is an integer kind, are rejected.
+func (d *decoder) numberByReflectType(t reflect.Type) (interface{}, interface{}, bool) {
 	if d.lexer.IsNull() {
 		d.lexer.Skip()
-		return nil, true
+		return nil, nil, true
+	}
+	if !d.isNumberToken() {
+		v := d.interfaceValue()
+		addUnexpectedTypeLexerError(d.lexer, t)
+		return v, v, false
+	}
+	s := string(d.lexer.Raw())
+	converted, err := parseNumber(s, t)
+	if err != nil {
+		d.lexer.AddNonFatalError(err)
+		v, _ := convertNumber(s, d.options.numberMode)
+		return v, v, false
+	}
+	return converted, converted, true
+}
+
+// quotedValueByReflectType decodes the value of a field tagged with the string option,
+// which is expected to be stored as JSON inside a JSON string.
+func (d *decoder) quotedValueByReflectType(t reflect.Type) (interface{}, interface{}, bool) {
+	v := d.interfaceValue()
+	if v == nil {
+		return nil, nil, true
+	}
+	str, ok := v.(string)
+	if !ok {
+		addInvalidQuotedLexerError(d.lexer, t)
+		return v, v, false
+	}
+	converted, ok := convertQuoted(str, t)
+	if !ok {
+		addInvalidQuotedLexerError(d.lexer, t)
+		return v, v, false
+	}
+	return converted, converted, true
+}
+
+func (d *decoder) buildSlice(sliceType reflect.Type) (interface{}, interface{}, bool) {
+	if d.lexer.IsNull() {
+		d.lexer.Skip()
+		return nil, nil, true
+	}
+	if isByteSlice(sliceType) && d.isStringToken() {
+		return d.buildByteSlice(sliceType)
 	}
 	if !d.lexer.IsDelim('[') {
 		addUnexpectedTypeLexerError(d.lexer, sliceType)
-		return d.lexer.Interface(), false
+		v := d.interfaceValue()
+		return v, v, false
 	}
 	elemType := sliceType.Elem()
+	nested := d.isNestedResult(elemType)
 	d.lexer.Delim('[')
 	var sliceValue reflect.Value
+	var results []interface{}
 	if !d.lexer.IsDelim(']') {
 		sliceValue = reflect.MakeSlice(sliceType, 0, 4)
 	} else {
 		sliceValue = reflect.MakeSlice(sliceType, 0, 0)
 	}
+	if nested {
+		results = make([]interface{}, 0, sliceValue.Cap())
+	}
 	for !d.lexer.IsDelim(']') {
-		current, valid := d.valueByReflectType(elemType, false)
+		current, currentResult, valid := d.valueByReflectType(elemType, false, reflect.Value{})
 		if !valid {
 			if d.options.mode != ModeFailOverToOriginalValue {
 				d.drainLexerArray(nil)
-				return nil, true
+				return nil, nil, true
+			}
+			if !nested {
+				results = d.cloneReflectArray(sliceValue, -1)
 			}
-			result := d.cloneReflectArray(sliceValue, -1)
-			result = append(result, current)
-			return d.drainLexerArray(result), true
+			results = append(results, current)
+			result := d.drainLexerArray(results)
+			return result, result, true
 		}
 		sliceValue = reflect.Append(sliceValue, safeReflectValue(elemType, current))
+		if nested {
+			results = append(results, currentResult)
+		}
 		d.lexer.WantComma()
 	}
 	d.lexer.Delim(']')
-	return sliceValue.Interface(), true
+	if nested {
+		return sliceValue.Interface(), results, true
+	}
+	result := sliceValue.Interface()
+	return result, result, true
 }
 
-func (d *decoder) buildArray(arrayType reflect.Type) (interface{}, bool) {
+// buildByteSlice decodes a base64 encoded JSON string into a byte slice, the same way json.Unmarshal does.
+func (d *decoder) buildByteSlice(sliceType reflect.Type) (interface{}, interface{}, bool) {
+	str := d.lexer.String()
+	converted, err := decodeBase64(str, sliceType)
+	if err != nil {
+		d.lexer.AddNonFatalError(err)
+		return str, str, false
+	}
+	return converted, converted, true
+}
+
+func (d *decoder) buildArray(arrayType reflect.Type) (interface{}, interface{}, bool) {
 	if d.lexer.IsNull() {
 		d.lexer.Skip()
-		return nil, true
+		return nil, nil, true
 	}
 	if !d.lexer.IsDelim('[') {
 		addUnexpectedTypeLexerError(d.lexer, arrayType)
-		return d.lexer.Interface(), false
+		v := d.interfaceValue()
+		return v, v, false
 	}
 	elemType := arrayType.Elem()
+	nested := d.isNestedResult(elemType)
 	arrayValue := reflect.New(arrayType).Elem()
+	var results []interface{}
+	if nested {
+		results = make([]interface{}, 0, arrayType.Len())
+	}
 	d.lexer.Delim('[')
 	for i := 0; !d.lexer.IsDelim(']'); i++ {
-		current, valid := d.valueByReflectType(elemType, false)
+		if i == arrayType.Len() {
+			if d.options.arrayOverflowPolicy == ArrayOverflowDrop {
+				for !d.lexer.IsDelim(']') {
+					d.lexer.SkipRecursive()
+					d.lexer.WantComma()
+				}
+				break
+			}
+			addArrayOverflowLexerError(d.lexer, arrayType)
+			if d.options.mode != ModeFailOverToOriginalValue {
+				d.lexer.SkipRecursive()
+				d.drainLexerArray(nil)
+				return nil, nil, true
+			}
+			if !nested {
+				results = d.cloneReflectArray(arrayValue, i)
+			}
+			results = append(results, d.interfaceValue())
+			result := d.drainLexerArray(results)
+			return result, result, true
+		}
+		current, currentResult, valid := d.valueByReflectType(elemType, false, reflect.Value{})
 		if !valid {
 			if d.options.mode != ModeFailOverToOriginalValue {
 				d.drainLexerArray(nil)
-				return nil, true
+				return nil, nil, true
+			}
+			if !nested {
+				results = d.cloneReflectArray(arrayValue, i)
 			}
-			result := d.cloneReflectArray(arrayValue, i)
-			result = append(result, current)
-			return d.drainLexerArray(result), true
+			results = append(results, current)
+			result := d.drainLexerArray(results)
+			return result, result, true
 		}
 		if current != nil {
 			arrayValue.Index(i).Set(reflect.ValueOf(current))
 		}
+		if nested {
+			results = append(results, currentResult)
+		}
 		d.lexer.WantComma()
 	}
 	d.lexer.Delim(']')
-	return arrayValue.Interface(), true
+	if nested {
+		return arrayValue.Interface(), results, true
+	}
+	result := arrayValue.Interface()
+	return result, result, true
 }
 
-func (d *decoder) buildMap(mapType reflect.Type) (interface{}, bool) {
+func (d *decoder) buildMap(mapType reflect.Type, existing reflect.Value) (interface{}, interface{}, bool) {
 	if d.lexer.IsNull() {
 		d.lexer.Skip()
-		return nil, true
+		return nil, nil, true
 	}
 	if !d.lexer.IsDelim('{') {
 		addUnexpectedTypeLexerError(d.lexer, mapType)
-		return d.lexer.Interface(), false
+		v := d.interfaceValue()
+		return v, v, false
 	}
 	d.lexer.Delim('{')
 	keyType := mapType.Key()
 	valueType := mapType.Elem()
-	mapValue := reflect.MakeMap(mapType)
+	nested := d.isNestedResult(valueType)
+	mapValue := existing
+	if !mapValue.IsValid() || mapValue.IsNil() {
+		mapValue = reflect.MakeMap(mapType)
+	}
+	// with ModeFailOverToOriginalValue, results holds the entries decoded so far, rather than those of the
+	// existing map that values are merged into, so that the original value only holds entries of the input
+	var results map[string]interface{}
+	var resultKeys *[]string
+	if nested || d.options.mode == ModeFailOverToOriginalValue {
+		results = make(map[string]interface{})
+		resultKeys = d.newResultKeys()
+	}
+	seen := d.newSeenKeys()
 	for !d.lexer.IsDelim('}') {
-		key, valid := d.valueByReflectType(keyType, false)
+		rawKey := d.stringValue()
+		d.lexer.WantColon()
+		if seen != nil && d.skipDuplicateKey(seen, rawKey) {
+			d.lexer.SkipRecursive()
+			d.lexer.WantComma()
+			continue
+		}
+		key, valid := d.mapKeyByReflectType(rawKey, keyType)
 		if !valid {
 			if d.options.mode != ModeFailOverToOriginalValue {
-				d.lexer.WantColon()
 				d.lexer.Interface()
 				d.lexer.WantComma()
-				d.drainLexerMap(make(map[string]interface{}))
-				return nil, true
+				d.drainLexerMap(make(map[string]interface{}), nil, nil)
+				return nil, nil, true
 			}
-			strKey, _ := key.(string)
-			d.lexer.WantColon()
-			value := d.lexer.Interface()
-			result := d.cloneReflectMap(mapValue)
-			result[strKey] = value
+			value := d.interfaceValue()
+			setResult(results, resultKeys, rawKey, value)
 			d.lexer.WantComma()
-			d.drainLexerMap(result)
-			return result, true
+			d.drainLexerMap(results, resultKeys, seen)
+			result := orderedResult(results, resultKeys)
+			return result, result, true
+		}
+		if d.options.fieldSet != nil {
+			d.path = append(d.path, rawKey)
+		}
+		value, valueResult, valid := d.valueByReflectType(valueType, false, reflect.Value{})
+		if d.options.fieldSet != nil {
+			d.path = d.path[:len(d.path)-1]
 		}
-		d.lexer.WantColon()
-		value, valid := d.valueByReflectType(valueType, false)
 		if !valid {
 			if d.options.mode != ModeFailOverToOriginalValue {
 				d.lexer.WantComma()
-				d.drainLexerMap(make(map[string]interface{}))
-				return nil, true
+				d.drainLexerMap(make(map[string]interface{}), nil, nil)
+				return nil, nil, true
 			}
-			strKey, _ := key.(string)
-			result := d.cloneReflectMap(mapValue)
-			result[strKey] = value
+			setResult(results, resultKeys, rawKey, value)
 			d.lexer.WantComma()
-			d.drainLexerMap(result)
-			return result, true
+			d.drainLexerMap(results, resultKeys, seen)
+			result := orderedResult(results, resultKeys)
+			return result, result, true
 		}
 		mapValue.SetMapIndex(safeReflectValue(keyType, key), safeReflectValue(valueType, value))
+		if nested {
+			setResult(results, resultKeys, rawKey, valueResult)
+		} else if results != nil {
+			setResult(results, resultKeys, rawKey, value)
+		}
 		d.lexer.WantComma()
 	}
 	d.lexer.Delim('}')
-	return mapValue.Interface(), true
+	if nested {
+		return mapValue.Interface(), orderedResult(results, resultKeys), true
+	}
+	result := mapValue.Interface()
+	return result, result, true
 }
 
-func (d *decoder) buildStruct(structType reflect.Type) (interface{}, bool) {
+// mapKeyByReflectType decodes an object key into a value of the map key type t, following json.Unmarshal.
+// Types implementing encoding.TextUnmarshaler are decoded using it, while string and integer kinds are converted.
+func (d *decoder) mapKeyByReflectType(key string, t reflect.Type) (interface{}, bool) {
+	if isTextUnmarshaler(t) {
+		result, err := unmarshalText(t, key)
+		if err != nil {
+			d.lexer.AddNonFatalError(err)
+			return nil, false
+		}
+		return result, true
+	}
+	if !isMapKeyKind(t.Kind()) {
+		addUnsupportedTypeLexerError(d.lexer, t)
+		return nil, false
+	}
+	result, ok := convertMapKey(key, t)
+	if !ok {
+		addUnexpectedTypeLexerError(d.lexer, t)
+	}
+	return result, ok
+}
+
+func (d *decoder) buildStruct(structType reflect.Type, existing reflect.Value) (interface{}, interface{}, bool) {
 	if d.lexer.IsNull() {
 		d.lexer.Skip()
-		return nil, true
+		return nil, nil, true
 	}
 	if !d.lexer.IsDelim('{') {
 		addUnexpectedTypeLexerError(d.lexer, structType)
-		return d.lexer.Interface(), false
+		v := d.interfaceValue()
+		return v, v, false
+	}
+	var value interface{}
+	if existing.IsValid() {
+		value = existing.Addr().Interface()
+	} else {
+		value = reflect.New(structType).Interface()
+	}
+	if !d.options.nestedResults {
+		result, valid := d.populateStruct(value, nil, nil)
+		return result, result, valid
 	}
-	value := reflect.New(structType).Interface()
-	return d.populateStruct(value, nil)
+	nestedResult := make(map[string]interface{})
+	keys := d.newResultKeys()
+	result, valid := d.populateStruct(value, nestedResult, keys)
+	if !valid {
+		return result, result, false
+	}
+	return result, orderedResult(nestedResult, keys), true
+}
+
+func (d *decoder) isNestedResult(t reflect.Type) bool {
+	return d.options.nestedResults && containsStruct(t, unmarshalerType, textUnmarshalerType, numberUnmarshalerType)
 }
 
 func (d *decoder) valueFromCustomUnmarshaler(unmarshaler json.Unmarshaler) {
@@ -317,6 +630,225 @@ func (d *decoder) valueFromCustomUnmarshaler(unmarshaler json.Unmarshaler) {
 	}
 }
 
+// valueFromTextUnmarshaler decodes a JSON string into a value of type t using its
+// encoding.TextUnmarshaler implementation. Any other JSON type than string or null is rejected.
+func (d *decoder) valueFromTextUnmarshaler(t reflect.Type) (interface{}, interface{}, bool) {
+	v := d.interfaceValue()
+	if v == nil {
+		return nil, nil, true
+	}
+	str, ok := v.(string)
+	if !ok {
+		addUnexpectedTypeLexerError(d.lexer, t)
+		return v, v, false
+	}
+	result, err := unmarshalText(t, str)
+	if err != nil {
+		d.lexer.AddNonFatalError(err)
+		return v, v, false
+	}
+	return result, result, true
+}
+
+// valueFromNumberUnmarshaler decodes a JSON number into a value of type t from the text of the number,
+// see UnmarshalerFromJSONNumber. Any other JSON type than number or null is rejected.
+func (d *decoder) valueFromNumberUnmarshaler(t reflect.Type) (interface{}, interface{}, bool) {
+	if d.lexer.IsNull() {
+		d.lexer.Skip()
+		return nil, nil, true
+	}
+	if !d.isNumberToken() {
+		v := d.interfaceValue()
+		addUnexpectedTypeLexerError(d.lexer, t)
+		return v, v, false
+	}
+	s := string(d.lexer.Raw())
+	result, err := unmarshalNumber(t, s)
+	if err != nil {
+		d.lexer.AddNonFatalError(err)
+		v, _ := convertNumber(s, d.options.numberMode)
+		return v, v, false
+	}
+	return result, result, true
+}
+
+// unknownValue decodes the value of a field that does not exist in the struct, either into an interface{}
+// value or, with the lazyUnknownFields and rawUnknownFields options, into a *Lazy or a json.RawMessage
+// holding the raw value.
+func (d *decoder) unknownValue() interface{} {
+	if !d.options.lazyUnknownFields && !d.options.rawUnknownFields {
+		return d.interfaceValue()
+	}
+	raw := d.lexer.Raw()
+	if raw == nil {
+		return nil
+	}
+	if !d.options.zeroCopyRawUnknownFields {
+		raw = append([]byte(nil), raw...)
+	}
+	if d.options.lazyUnknownFields {
+		return &Lazy{raw: raw, options: d.options}
+	}
+	return json.RawMessage(raw)
+}
+
+// interfaceValue decodes the next JSON value into an interface{} value, the same way jlexer.Interface does,
+// except that numbers are represented according to the number mode, duplicate keys are handled according
+// to the duplicate key policy, invalid UTF-8 is handled according to the invalid UTF-8 policy, and objects
+// are decoded into *OrderedMap values with the orderedResults option.
+func (d *decoder) interfaceValue() interface{} {
+	if d.options.numberMode == NumberModeFloat64 && d.options.duplicateKeys == DuplicateLast &&
+		d.options.invalidUTF8 == InvalidUTF8Pass && !d.options.orderedResults {
+		return d.lexer.Interface()
+	}
+	if d.lexer.IsNull() {
+		d.lexer.Skip()
+		return nil
+	}
+	if !d.lexer.Ok() {
+		return nil
+	}
+	if d.lexer.IsDelim('{') {
+		d.lexer.Delim('{')
+		result := make(map[string]interface{})
+		keys := d.newResultKeys()
+		for !d.lexer.IsDelim('}') {
+			key := d.stringValue()
+			d.lexer.WantColon()
+			if _, exists := result[key]; exists && d.skipDuplicateValue(key) {
+				d.lexer.SkipRecursive()
+			} else {
+				setResult(result, keys, key, d.interfaceValue())
+			}
+			d.lexer.WantComma()
+		}
+		d.lexer.Delim('}')
+		return orderedResult(result, keys)
+	}
+	if d.lexer.IsDelim('[') {
+		d.lexer.Delim('[')
+		result := make([]interface{}, 0)
+		for !d.lexer.IsDelim(']') {
+			result = append(result, d.interfaceValue())
+			d.lexer.WantComma()
+		}
+		d.lexer.Delim(']')
+		return result
+	}
+	if d.isStringToken() {
+		return d.stringValue()
+	}
+	if !d.isNumberToken() {
+		return d.lexer.Interface()
+	}
+	value, err := convertNumber(string(d.lexer.Raw()), d.options.numberMode)
+	if err != nil {
+		d.lexer.AddNonFatalError(err)
+	}
+	return value
+}
+
+// fieldName reads an object key, the same way stringValue does. With InvalidUTF8Pass, the key is not copied
+// and may point to the input data.
+func (d *decoder) fieldName() string {
+	if d.options.invalidUTF8 == InvalidUTF8Pass {
+		return d.lexer.UnsafeFieldName(false)
+	}
+	return d.stringValue()
+}
+
+// extrasKey returns a copy of an object key read by fieldName, to be stored in an extras field, which is part
+// of the struct and may outlive the input data. Keys read with policies other than InvalidUTF8Pass are already
+// copies, and are returned as is.
+func (d *decoder) extrasKey(key string) string {
+	if d.options.invalidUTF8 != InvalidUTF8Pass {
+		return key
+	}
+	return string([]byte(key))
+}
+
+// stringValue reads a JSON string, handling invalid UTF-8 according to the invalid UTF-8 policy.
+// With policies other than InvalidUTF8Pass, the raw string literal is read first, since jlexer decodes
+// escaped UTF-16 surrogates that are not part of a valid pair as U+FFFD.
+func (d *decoder) stringValue() string {
+	if d.options.invalidUTF8 == InvalidUTF8Pass || d.lexer.IsNull() || !d.isStringToken() {
+		return d.lexer.String()
+	}
+	raw := d.lexer.Raw()
+	lexer := jlexer.Lexer{Data: raw}
+	str := lexer.String()
+	if err := lexer.Error(); err != nil {
+		d.lexer.AddError(err)
+		return ""
+	}
+	if !strings.ContainsRune(str, utf8.RuneError) {
+		return str
+	}
+	if d.options.invalidUTF8 == InvalidUTF8Error && (!utf8.ValidString(str) || hasLoneSurrogate(raw)) {
+		addInvalidUTF8LexerError(d.lexer, str)
+	}
+	return replaceInvalidUTF8(str)
+}
+
+// newResultKeys returns a slice for recording the keys of a result object in order,
+// or nil if the orderedResults option is not set.
+func (d *decoder) newResultKeys() *[]string {
+	if !d.options.orderedResults {
+		return nil
+	}
+	return new([]string)
+}
+
+// newSeenKeys returns a set for tracking the keys of a JSON object, or nil if the duplicate
+// key policy does not require tracking them.
+func (d *decoder) newSeenKeys() map[string]struct{} {
+	if d.options.duplicateKeys == DuplicateLast {
+		return nil
+	}
+	return make(map[string]struct{})
+}
+
+// skipDuplicateKey records key in seen, and reports whether its value should be skipped
+// according to the duplicate key policy in case it was already recorded.
+func (d *decoder) skipDuplicateKey(seen map[string]struct{}, key string) bool {
+	if _, exists := seen[key]; exists {
+		return d.skipDuplicateValue(key)
+	}
+	seen[key] = struct{}{}
+	return false
+}
+
+// skipDuplicateValue reports whether the value of a duplicate key should be skipped according
+// to the duplicate key policy, adding an error if the policy rejects duplicate keys.
+func (d *decoder) skipDuplicateValue(key string) bool {
+	switch d.options.duplicateKeys {
+	case DuplicateFirst:
+		return true
+	case DuplicateError:
+		addDuplicateKeyLexerError(d.lexer, key)
+		return true
+	}
+	return false
+}
+
+// isStringToken reports whether the current token, which must already be fetched, is a string.
+func (d *decoder) isStringToken() bool {
+	pos := d.lexer.GetPos()
+	return pos > 0 && d.lexer.Data[pos-1] == '"'
+}
+
+// isNumberToken reports whether the current token, which must already be fetched, is a number.
+// jlexer does not expose token kinds, so the kind is deduced from the last byte of the token -
+// numbers are the only tokens ending with a digit.
+func (d *decoder) isNumberToken() bool {
+	pos := d.lexer.GetPos()
+	if pos == 0 {
+		return false
+	}
+	c := d.lexer.Data[pos-1]
+	return c >= '0' && c <= '9'
+}
+
 func (d *decoder) cloneReflectArray(value reflect.Value, length int) []interface{} {
 	if length == -1 {
 		length = value.Len()
@@ -328,21 +860,10 @@ func (d *decoder) cloneReflectArray(value reflect.Value, length int) []interface
 	return result
 }
 
-func (d *decoder) cloneReflectMap(mapValue reflect.Value) map[string]interface{} {
-	l := mapValue.Len()
-	result := make(map[string]interface{}, l)
-	for _, key := range mapValue.MapKeys() {
-		value := mapValue.MapIndex(key)
-		strKey, _ := key.Interface().(string)
-		result[strKey] = value.Interface()
-	}
-	return result
-}
-
 func (d *decoder) drainLexerArray(target []interface{}) interface{} {
 	d.lexer.WantComma()
 	for !d.lexer.IsDelim(']') {
-		current := d.lexer.Interface()
+		current := d.interfaceValue()
 		target = append(target, current)
 		d.lexer.WantComma()
 	}
@@ -350,12 +871,17 @@ func (d *decoder) drainLexerArray(target []interface{}) interface{} {
 	return target
 }
 
-func (d *decoder) drainLexerMap(target map[string]interface{}) {
+func (d *decoder) drainLexerMap(target map[string]interface{}, keys *[]string, seen map[string]struct{}) {
 	for !d.lexer.IsDelim('}') {
-		key := d.lexer.String()
+		key := d.stringValue()
 		d.lexer.WantColon()
-		value := d.lexer.Interface()
-		target[key] = value
+		if seen != nil && d.skipDuplicateKey(seen, key) {
+			d.lexer.SkipRecursive()
+			d.lexer.WantComma()
+			continue
+		}
+		value := d.interfaceValue()
+		setResult(target, keys, key, value)
 		d.lexer.WantComma()
 	}
 	d.lexer.Delim('}')
@@ -5,7 +5,9 @@
 package marshmallow
 
 import (
+	"encoding/json"
 	"reflect"
+	"strconv"
 )
 
 // UnmarshalerFromJSONMap is the interface implemented by types
@@ -28,10 +30,13 @@ type UnmarshalerFromJSONMap interface {
 // - All input fields are stored in the resulting map, including fields that do not exist in the
 // struct pointed by v.
 // - UnmarshalFromJSONMap receive a JSON map instead of raw bytes. The given input map is assumed
-// to be a JSON map, meaning it should only contain the following types: bool, string, float64,
-// []interface, and map[string]interface{}. Other types will cause decoding to return unexpected results.
+// to be a JSON map, meaning it should only contain the following types: bool, string, float64, json.Number,
+// []interface, map[string]interface{} and *OrderedMap. Other types will cause decoding to return unexpected results.
 // - UnmarshalFromJSONMap only operates on struct values. It will reject all other types of v by
 // returning ErrInvalidValue.
+// - Fields that do not exist in a struct are also stored in its extras field, if it has one. An extras
+// field is a map[string]interface{} field tagged with `marshmallow:",extras"`. This applies to all structs,
+// including nested ones.
 // - UnmarshalFromJSONMap supports three types of Mode values. Each mode is self documented and affects
 // how UnmarshalFromJSONMap behaves.
 func UnmarshalFromJSONMap(data map[string]interface{}, v interface{}, options ...UnmarshalOption) (map[string]interface{}, error) {
@@ -70,62 +75,133 @@ func (m *mapDecoder) populateStruct(path []string, data map[string]interface{},
 	if doPopulate {
 		structValue = reflectStructValue(structInstance)
 	}
-	fields := mapStructFields(structInstance)
+	info := mapStructFields(structInstance)
+	knownResult := result != nil && m.options.resultContent != ResultUnknownOnly
+	unknownResult := result != nil && m.options.resultContent != ResultKnownOnly
+	var extras reflect.Value
 	for key, inputValue := range data {
-		refInfo, exists := fields[key]
+		refInfo, exists := info.lookup(key, m.options.caseInsensitiveKeys)
 		if exists {
-			value, isValidType := m.valueByReflectType(append(path, key), inputValue, refInfo.t, false)
+			var value, resultValue interface{}
+			var isValidType bool
+			isNull := inputValue == nil
+			fieldPath := append(path, refInfo.name)
+			if m.options.fieldSet != nil {
+				m.options.fieldSet.add(fieldPath, isNull)
+			}
+			if isNull && isNullable(refInfo.t) {
+				if doPopulate {
+					setNull(refInfo, structValue)
+				}
+				isValidType = true
+			} else if refInfo.quoted {
+				value, resultValue, isValidType = m.quotedValueByReflectType(fieldPath, inputValue, refInfo.t)
+			} else {
+				var existing reflect.Value
+				if doPopulate && !m.options.replaceExisting {
+					existing = existingValue(refInfo, structValue)
+				}
+				value, resultValue, isValidType = m.valueByReflectType(fieldPath, inputValue, refInfo.t, false, existing)
+			}
 			if isValidType {
 				if value != nil && doPopulate {
 					field := refInfo.field(structValue)
 					assignValue(field, value)
 				}
-				if result != nil {
-					result[key] = value
+				if knownResult && (!isNull || !m.options.omitNullResults) {
+					result[key] = m.nativeResultValue(fieldPath, resultValue)
 				}
 			} else {
 				switch m.options.mode {
 				case ModeFailOnFirstError:
 					return nil, false
 				case ModeFailOverToOriginalValue:
-					if result != nil {
+					if knownResult {
 						result[key] = value
-					} else {
+					} else if result == nil {
 						return data, false
 					}
 				}
 			}
-		} else {
-			if result != nil {
+		} else if unknownResult || (info.extras != nil && doPopulate) {
+			inputValue = m.interfaceValue(inputValue)
+			if unknownResult {
 				result[key] = inputValue
 			}
+			if info.extras != nil && doPopulate {
+				if !extras.IsValid() {
+					extras = info.extrasMap(structValue)
+				}
+				if extras.IsValid() {
+					extras.SetMapIndex(reflect.ValueOf(key), safeReflectValue(info.extras.t.Elem(), inputValue))
+				}
+			}
 		}
 	}
 	return structInstance, true
 }
 
-func (m *mapDecoder) valueByReflectType(path []string, v interface{}, t reflect.Type, isPtr bool) (interface{}, bool) {
+// nativeResultValue returns the value stored in the result map for a known field. With the nativeResultTypes
+// option, v is converted into its JSON map representation.
+func (m *mapDecoder) nativeResultValue(path []string, v interface{}) interface{} {
+	if !m.options.nativeResultTypes {
+		return v
+	}
+	native, err := jsonMapValue(v, m.options.numberMode)
+	if err != nil {
+		m.addError(newResultValueParseError(err, path))
+		return nil
+	}
+	return native
+}
+
+// valueByReflectType converts the JSON map value v into a value of type t. If existing is valid, it holds the
+// current value of the target, and v is merged into it in place where json.Unmarshal would do so.
+func (m *mapDecoder) valueByReflectType(path []string, v interface{}, t reflect.Type, isPtr bool, existing reflect.Value) (interface{}, interface{}, bool) {
+	if isNumberUnmarshaler(t) {
+		return m.valueFromNumberUnmarshaler(path, v, t)
+	}
+	if existing.IsValid() && isMergeableUnmarshaler(existing, unmarshalerFromJSONMapType) {
+		target := existing
+		if t.Kind() != reflect.Ptr {
+			target = existing.Addr()
+		}
+		m.valueFromCustomUnmarshaler(v, target.Interface().(UnmarshalerFromJSONMap))
+		result := existing.Interface()
+		return result, result, true
+	}
 	if t.Implements(unmarshalerFromJSONMapType) {
 		result := reflect.New(t.Elem()).Interface()
 		m.valueFromCustomUnmarshaler(v, result.(UnmarshalerFromJSONMap))
-		return result, true
+		return result, result, true
 	}
 	if reflect.PtrTo(t).Implements(unmarshalerFromJSONMapType) {
 		value := reflect.New(t)
 		m.valueFromCustomUnmarshaler(v, value.Interface().(UnmarshalerFromJSONMap))
-		return value.Elem().Interface(), true
+		result := value.Elem().Interface()
+		return result, result, true
+	}
+	if isTextUnmarshaler(t) {
+		return m.valueFromTextUnmarshaler(path, v, t)
 	}
 	kind := t.Kind()
+	if kind == reflect.Interface {
+		value := m.interfaceValue(v)
+		return value, value, true
+	}
+	if isNumberKind(kind) {
+		return m.numberByReflectType(path, v, t)
+	}
 	if converter := primitiveConverters[kind]; converter != nil {
 		if v == nil {
-			return nil, true
+			return nil, nil, true
 		}
 		converted, ok := converter(v)
 		if !ok {
 			m.addError(newUnexpectedTypeParseError(t, path))
-			return v, false
+			return v, v, false
 		}
-		return converted, true
+		return converted, converted, true
 	}
 	switch kind {
 	case reflect.Slice:
@@ -133,134 +209,286 @@ func (m *mapDecoder) valueByReflectType(path []string, v interface{}, t reflect.
 	case reflect.Array:
 		return m.buildArray(path, v, t)
 	case reflect.Map:
-		return m.buildMap(path, v, t)
+		return m.buildMap(path, v, t, existing)
 	case reflect.Struct:
-		value, valid := m.buildStruct(path, v, t)
+		value, resultValue, valid := m.buildStruct(path, v, t, existing)
 		if value == nil {
-			return nil, valid
+			return nil, nil, valid
 		}
 		if !valid {
-			return value, false
+			return value, value, false
+		}
+		value = reflect.ValueOf(value).Elem().Interface()
+		if !m.options.nestedResults {
+			resultValue = value
 		}
-		return reflect.ValueOf(value).Elem().Interface(), valid
+		return value, resultValue, valid
 	case reflect.Ptr:
+		var elem reflect.Value
+		if existing.IsValid() && !existing.IsNil() {
+			elem = existing.Elem()
+		}
 		if t.Elem().Kind() == reflect.Struct {
-			return m.buildStruct(path, v, t.Elem())
+			return m.buildStruct(path, v, t.Elem(), elem)
 		}
-		value, valid := m.valueByReflectType(path, v, t.Elem(), true)
+		value, resultValue, valid := m.valueByReflectType(path, v, t.Elem(), true, elem)
 		if value == nil {
-			return nil, valid
+			return nil, nil, valid
 		}
 		if !valid {
-			return value, false
+			return value, value, false
+		}
+		result := pointerTo(value, existing)
+		if !m.isNestedResult(t.Elem()) {
+			resultValue = result.Interface()
 		}
-		result := reflect.New(reflect.TypeOf(value))
-		result.Elem().Set(reflect.ValueOf(value))
-		return result.Interface(), valid
+		return result.Interface(), resultValue, valid
 	}
 	m.addError(newUnsupportedTypeParseError(t, path))
-	return nil, false
+	return nil, nil, false
 }
 
-func (m *mapDecoder) buildSlice(path []string, v interface{}, sliceType reflect.Type) (interface{}, bool) {
+// numberByReflectType converts a JSON map number, which is either a float64, a json.Number, or an int64 produced
+// by NumberModeInt64IfIntegral, into a value of the numeric kind of t.
+// Numbers that do not fit in t, or are not integral while t is an integer kind, are rejected.
+func (m *mapDecoder) numberByReflectType(path []string, v interface{}, t reflect.Type) (interface{}, interface{}, bool) {
+	var converted interface{}
+	var err error
+	switch value := v.(type) {
+	case nil:
+		return nil, nil, true
+	case float64:
+		converted, err = convertFloat(value, t)
+	case json.Number:
+		converted, err = parseNumber(string(value), t)
+	case int64:
+		converted, err = parseNumber(strconv.FormatInt(value, 10), t)
+	default:
+		m.addError(newUnexpectedTypeParseError(t, path))
+		return v, v, false
+	}
+	if err != nil {
+		m.addError(newNumberParseError(err, path))
+		return v, v, false
+	}
+	return converted, converted, true
+}
+
+// quotedValueByReflectType decodes the value of a field tagged with the string option,
+// which is expected to be stored as JSON inside a JSON string.
+func (m *mapDecoder) quotedValueByReflectType(path []string, v interface{}, t reflect.Type) (interface{}, interface{}, bool) {
 	if v == nil {
-		return nil, true
+		return nil, nil, true
+	}
+	str, ok := v.(string)
+	if !ok {
+		m.addError(newInvalidQuotedParseError(t, path))
+		return v, v, false
+	}
+	converted, ok := convertQuoted(str, t)
+	if !ok {
+		m.addError(newInvalidQuotedParseError(t, path))
+		return v, v, false
+	}
+	return converted, converted, true
+}
+
+func (m *mapDecoder) buildSlice(path []string, v interface{}, sliceType reflect.Type) (interface{}, interface{}, bool) {
+	if v == nil {
+		return nil, nil, true
+	}
+	if str, ok := v.(string); ok && isByteSlice(sliceType) {
+		converted, err := decodeBase64(str, sliceType)
+		if err != nil {
+			m.addError(newInvalidBase64ParseError(err, path))
+			return v, v, false
+		}
+		return converted, converted, true
 	}
 	arr, ok := v.([]interface{})
 	if !ok {
 		m.addError(newUnexpectedTypeParseError(sliceType, path))
-		return v, false
+		return v, v, false
 	}
 	elemType := sliceType.Elem()
+	nested := m.isNestedResult(elemType)
 	var sliceValue reflect.Value
 	if len(arr) > 0 {
 		sliceValue = reflect.MakeSlice(sliceType, 0, 4)
 	} else {
 		sliceValue = reflect.MakeSlice(sliceType, 0, 0)
 	}
+	var results []interface{}
+	if nested {
+		results = make([]interface{}, 0, len(arr))
+	}
 	for _, element := range arr {
-		current, valid := m.valueByReflectType(path, element, elemType, false)
+		current, currentResult, valid := m.valueByReflectType(path, element, elemType, false, reflect.Value{})
 		if !valid {
 			if m.options.mode != ModeFailOverToOriginalValue {
-				return nil, true
+				return nil, nil, true
 			}
-			return v, true
+			return v, v, true
 		}
 		sliceValue = reflect.Append(sliceValue, safeReflectValue(elemType, current))
+		if nested {
+			results = append(results, currentResult)
+		}
+	}
+	if nested {
+		return sliceValue.Interface(), results, true
 	}
-	return sliceValue.Interface(), true
+	result := sliceValue.Interface()
+	return result, result, true
 }
 
-func (m *mapDecoder) buildArray(path []string, v interface{}, arrayType reflect.Type) (interface{}, bool) {
+func (m *mapDecoder) buildArray(path []string, v interface{}, arrayType reflect.Type) (interface{}, interface{}, bool) {
 	if v == nil {
-		return nil, true
+		return nil, nil, true
 	}
 	arr, ok := v.([]interface{})
 	if !ok {
 		m.addError(newUnexpectedTypeParseError(arrayType, path))
-		return v, false
+		return v, v, false
 	}
 	elemType := arrayType.Elem()
+	nested := m.isNestedResult(elemType)
 	arrayValue := reflect.New(arrayType).Elem()
+	var results []interface{}
+	if nested {
+		results = make([]interface{}, 0, len(arr))
+	}
 	for i, element := range arr {
-		current, valid := m.valueByReflectType(path, element, elemType, false)
+		if i == arrayType.Len() {
+			if m.options.arrayOverflowPolicy == ArrayOverflowDrop {
+				break
+			}
+			m.addError(newArrayOverflowParseError(arrayType, path))
+			if m.options.mode != ModeFailOverToOriginalValue {
+				return nil, nil, true
+			}
+			return v, v, true
+		}
+		current, currentResult, valid := m.valueByReflectType(path, element, elemType, false, reflect.Value{})
 		if !valid {
 			if m.options.mode != ModeFailOverToOriginalValue {
-				return nil, true
+				return nil, nil, true
 			}
-			return v, true
+			return v, v, true
 		}
 		if current != nil {
 			arrayValue.Index(i).Set(reflect.ValueOf(current))
 		}
+		if nested {
+			results = append(results, currentResult)
+		}
+	}
+	if nested {
+		return arrayValue.Interface(), results, true
 	}
-	return arrayValue.Interface(), true
+	result := arrayValue.Interface()
+	return result, result, true
 }
 
-func (m *mapDecoder) buildMap(path []string, v interface{}, mapType reflect.Type) (interface{}, bool) {
+func (m *mapDecoder) buildMap(path []string, v interface{}, mapType reflect.Type, existing reflect.Value) (interface{}, interface{}, bool) {
 	if v == nil {
-		return nil, true
+		return nil, nil, true
 	}
-	mp, ok := v.(map[string]interface{})
+	mp, ok := jsonMapObject(v)
 	if !ok {
 		m.addError(newUnexpectedTypeParseError(mapType, path))
-		return v, false
+		return v, v, false
 	}
 	keyType := mapType.Key()
 	valueType := mapType.Elem()
-	mapValue := reflect.MakeMap(mapType)
+	nested := m.isNestedResult(valueType)
+	mapValue := existing
+	if !mapValue.IsValid() || mapValue.IsNil() {
+		mapValue = reflect.MakeMap(mapType)
+	}
+	var results map[string]interface{}
+	if nested {
+		results = make(map[string]interface{}, len(mp))
+	}
 	for inputKey, inputValue := range mp {
 		keyPath := append(path, inputKey)
-		key, valid := m.valueByReflectType(keyPath, inputKey, keyType, false)
+		key, valid := m.mapKeyByReflectType(keyPath, inputKey, keyType)
 		if !valid {
 			if m.options.mode != ModeFailOverToOriginalValue {
-				return nil, true
+				return nil, nil, true
 			}
-			return v, true
+			return v, v, true
 		}
-		value, valid := m.valueByReflectType(keyPath, inputValue, valueType, false)
+		value, valueResult, valid := m.valueByReflectType(keyPath, inputValue, valueType, false, reflect.Value{})
 		if !valid {
 			if m.options.mode != ModeFailOverToOriginalValue {
-				return nil, true
+				return nil, nil, true
 			}
-			return v, true
+			return v, v, true
 		}
 		mapValue.SetMapIndex(safeReflectValue(keyType, key), safeReflectValue(valueType, value))
+		if nested {
+			results[inputKey] = valueResult
+		}
+	}
+	if nested {
+		return mapValue.Interface(), results, true
 	}
-	return mapValue.Interface(), true
+	result := mapValue.Interface()
+	return result, result, true
 }
 
-func (m *mapDecoder) buildStruct(path []string, v interface{}, structType reflect.Type) (interface{}, bool) {
+// mapKeyByReflectType decodes an object key into a value of the map key type t, following json.Unmarshal.
+// Types implementing encoding.TextUnmarshaler are decoded using it, while string and integer kinds are converted.
+func (m *mapDecoder) mapKeyByReflectType(path []string, key string, t reflect.Type) (interface{}, bool) {
+	if isTextUnmarshaler(t) {
+		result, err := unmarshalText(t, key)
+		if err != nil {
+			m.addError(newTextUnmarshalerParseError(err, path))
+			return nil, false
+		}
+		return result, true
+	}
+	if !isMapKeyKind(t.Kind()) {
+		m.addError(newUnsupportedTypeParseError(t, path))
+		return nil, false
+	}
+	result, ok := convertMapKey(key, t)
+	if !ok {
+		m.addError(newUnexpectedTypeParseError(t, path))
+	}
+	return result, ok
+}
+
+func (m *mapDecoder) buildStruct(path []string, v interface{}, structType reflect.Type, existing reflect.Value) (interface{}, interface{}, bool) {
 	if v == nil {
-		return nil, true
+		return nil, nil, true
 	}
-	mp, ok := v.(map[string]interface{})
+	mp, ok := jsonMapObject(v)
 	if !ok {
 		m.addError(newUnexpectedTypeParseError(structType, path))
-		return v, false
+		return v, v, false
+	}
+	var value interface{}
+	if existing.IsValid() {
+		value = existing.Addr().Interface()
+	} else {
+		value = reflect.New(structType).Interface()
+	}
+	if !m.options.nestedResults {
+		result, valid := m.populateStruct(path, mp, value, nil)
+		return result, result, valid
+	}
+	nestedResult := make(map[string]interface{}, len(mp))
+	result, valid := m.populateStruct(path, mp, value, nestedResult)
+	if !valid {
+		return result, result, false
 	}
-	value := reflect.New(structType).Interface()
-	return m.populateStruct(path, mp, value, nil)
+	return result, nestedResult, true
+}
+
+func (m *mapDecoder) isNestedResult(t reflect.Type) bool {
+	return m.options.nestedResults && containsStruct(t, unmarshalerFromJSONMapType, textUnmarshalerType, numberUnmarshalerType)
 }
 
 func (m *mapDecoder) valueFromCustomUnmarshaler(data interface{}, unmarshaler UnmarshalerFromJSONMap) {
@@ -270,6 +498,86 @@ func (m *mapDecoder) valueFromCustomUnmarshaler(data interface{}, unmarshaler Un
 	}
 }
 
+// valueFromTextUnmarshaler decodes a JSON string into a value of type t using its
+// encoding.TextUnmarshaler implementation. Any other JSON type than string or null is rejected.
+func (m *mapDecoder) valueFromTextUnmarshaler(path []string, v interface{}, t reflect.Type) (interface{}, interface{}, bool) {
+	if v == nil {
+		return nil, nil, true
+	}
+	str, ok := v.(string)
+	if !ok {
+		m.addError(newUnexpectedTypeParseError(t, path))
+		return v, v, false
+	}
+	result, err := unmarshalText(t, str)
+	if err != nil {
+		m.addError(newTextUnmarshalerParseError(err, path))
+		return v, v, false
+	}
+	return result, result, true
+}
+
+// valueFromNumberUnmarshaler decodes a JSON number into a value of type t from the text of the number,
+// see UnmarshalerFromJSONNumber. Any other JSON type than number or null is rejected.
+func (m *mapDecoder) valueFromNumberUnmarshaler(path []string, v interface{}, t reflect.Type) (interface{}, interface{}, bool) {
+	if v == nil {
+		return nil, nil, true
+	}
+	s, ok := numberText(v)
+	if !ok {
+		m.addError(newUnexpectedTypeParseError(t, path))
+		return v, v, false
+	}
+	result, err := unmarshalNumber(t, s)
+	if err != nil {
+		m.addError(newNumberParseError(err, path))
+		return v, v, false
+	}
+	return result, result, true
+}
+
+// interfaceValue returns the JSON map value v with its numbers represented according to the number mode.
+// With NumberModeFloat64, v is returned as is. Otherwise, maps and slices holding numbers are copied.
+func (m *mapDecoder) interfaceValue(v interface{}) interface{} {
+	if m.options.numberMode == NumberModeFloat64 {
+		return v
+	}
+	switch value := v.(type) {
+	case float64:
+		if m.options.numberMode == NumberModeJSONNumber {
+			return json.Number(formatFloat(value))
+		}
+		if n, ok := integralInt64(value); ok {
+			return n
+		}
+	case json.Number:
+		converted, err := convertNumber(string(value), m.options.numberMode)
+		if err == nil {
+			return converted
+		}
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, element := range value {
+			result[key] = m.interfaceValue(element)
+		}
+		return result
+	case *OrderedMap:
+		result := &OrderedMap{}
+		value.Range(func(key string, element interface{}) bool {
+			result.Set(key, m.interfaceValue(element))
+			return true
+		})
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, element := range value {
+			result[i] = m.interfaceValue(element)
+		}
+		return result
+	}
+	return v
+}
+
 func (m *mapDecoder) addError(err error) {
 	if m.options.mode == ModeFailOnFirstError {
 		m.err = err
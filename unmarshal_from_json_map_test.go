@@ -5,7 +5,11 @@
 package marshmallow
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-test/deep"
+	"net"
 	"reflect"
 	"strings"
 	"testing"
@@ -2122,7 +2126,7 @@ func TestUnmarshalFromJSONMapInputVariations(t *testing.T) {
 					expectedMap[k] = v
 				}
 				structValue := reflectStructValue(actualStruct)
-				for name, refInfo := range mapStructFields(actualStruct) {
+				for name, refInfo := range mapStructFields(actualStruct).fields {
 					field := refInfo.field(structValue)
 					expectedMap[name] = field.Interface()
 				}
@@ -2258,3 +2262,1006 @@ func TestUnmarshalFromJSONMapEmbedding(t *testing.T) {
 		}
 	})
 }
+
+func TestUnmarshalFromJSONMapNestedResults(t *testing.T) {
+	input := map[string]interface{}{
+		"child":     map[string]interface{}{"field": "a", "extra": float64(1)},
+		"child_ptr": map[string]interface{}{"field": "b", "extra": []interface{}{float64(2)}},
+		"children": []interface{}{
+			map[string]interface{}{"field": "c", "extra": map[string]interface{}{"x": float64(3)}},
+		},
+		"array": []interface{}{map[string]interface{}{"field": "d", "extra": true}},
+		"map": map[string]interface{}{
+			"key": map[string]interface{}{"field": "e", "extra": "5"},
+		},
+		"numbers": []interface{}{float64(1), float64(2)},
+		"extra":   float64(6),
+	}
+	t.Run("test_nested_results", func(t *testing.T) {
+		p := nestedParent{}
+		result, err := UnmarshalFromJSONMap(input, &p, WithNestedResults(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if p.Child.Field != "a" || p.ChildPtr.Field != "b" || p.Children[0].Field != "c" ||
+			p.Array[0].Field != "d" || p.Map["key"].Field != "e" {
+			t.Errorf("missing nested values in struct %+v", p)
+		}
+		expected := make(map[string]interface{})
+		for k, v := range input {
+			expected[k] = v
+		}
+		expected["numbers"] = []int{1, 2}
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapExtras(t *testing.T) {
+	t.Run("test_extras_populated", func(t *testing.T) {
+		input := map[string]interface{}{
+			"field": "a",
+			"extra": float64(1),
+			"child": map[string]interface{}{"field": "b", "extra": []interface{}{float64(2)}},
+			"children": []interface{}{
+				map[string]interface{}{"field": "c", "extra": map[string]interface{}{"x": float64(3)}},
+				map[string]interface{}{"field": "d"},
+			},
+		}
+		p := extrasParent{}
+		result, err := UnmarshalFromJSONMap(input, &p)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := extrasParent{
+			Field:  "a",
+			Extras: map[string]interface{}{"extra": float64(1)},
+			Child: extrasChild{
+				Field:  "b",
+				Extras: map[string]interface{}{"extra": []interface{}{float64(2)}},
+			},
+			Children: []*extrasChild{
+				{Field: "c", Extras: map[string]interface{}{"extra": map[string]interface{}{"x": float64(3)}}},
+				{Field: "d"},
+			},
+		}
+		if diff := deep.Equal(p, expected); diff != nil {
+			t.Errorf("unexpected struct value:\n%s", strings.Join(diff, "\n"))
+		}
+		if len(result) != 4 || result["extra"] != float64(1) {
+			t.Errorf("unexpected result map %+v", result)
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapUntaggedFields(t *testing.T) {
+	t.Run("test_untagged_fields_mapped_by_name", func(t *testing.T) {
+		s := untaggedStruct{}
+		input := map[string]interface{}{"Name": "foo", "Age": float64(12), "renamed": true, "Ignored": "a", "internal": "b"}
+		result, err := UnmarshalFromJSONMap(input, &s)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := untaggedStruct{Name: "foo", Age: 12, Renamed: true}
+		if diff := deep.Equal(s, expected); diff != nil {
+			t.Errorf("unexpected struct value:\n%s", strings.Join(diff, "\n"))
+		}
+		expectedMap := map[string]interface{}{"Name": "foo", "Age": 12, "renamed": true, "Ignored": "a", "internal": "b"}
+		if diff := deep.Equal(result, expectedMap); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapCaseInsensitiveKeys(t *testing.T) {
+	t.Run("test_case_insensitive", func(t *testing.T) {
+		s := caseInsensitiveStruct{}
+		input := map[string]interface{}{"USERID": "a", "userid": "b", "NAME": "c"}
+		result, err := UnmarshalFromJSONMap(input, &s, WithCaseInsensitiveKeys(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := caseInsensitiveStruct{UserID: "a", UserId: "b", Name: "c"}
+		if s != expected {
+			t.Errorf("unexpected struct value %+v", s)
+		}
+		if diff := deep.Equal(result, input); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapQuotedFields(t *testing.T) {
+	ptr := uint8(7)
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		mode           Mode
+		expectedErr    bool
+		expectedStruct quotedStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name: "valid_values",
+			input: map[string]interface{}{
+				"id": "9007199254740993", "ptr": "7", "flag": "true", "amount": "1.5", "name": `"foo"`,
+			},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    false,
+			expectedStruct: quotedStruct{ID: 9007199254740993, Ptr: &ptr, Flag: true, Amount: 1.5, Name: "foo"},
+			expectedMap: map[string]interface{}{
+				"id": int64(9007199254740993), "ptr": &ptr, "flag": true, "amount": 1.5, "name": "foo",
+			},
+		},
+		{
+			name:           "ModeFailOnFirstError_unquoted_value",
+			input:          map[string]interface{}{"id": float64(12)},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: quotedStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOverToOriginalValue_invalid_values",
+			input:          map[string]interface{}{"id": float64(12), "ptr": "300", "name": "foo"},
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: quotedStruct{},
+			expectedMap:    map[string]interface{}{"id": float64(12), "ptr": "300", "name": "foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := quotedStruct{}
+			result, err := UnmarshalFromJSONMap(tt.input, &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapEmbeddingRules(t *testing.T) {
+	t.Run("test_embedding_rules_match_json", func(t *testing.T) {
+		input := map[string]interface{}{
+			"ptr_field": "a", "value_field": "b", "tagged": map[string]interface{}{"value_field": "c"},
+			"shadowed": "d", "Ambiguous": "e", "TaggedWins": "f",
+		}
+		actual := embeddingParent{}
+		_, err := UnmarshalFromJSONMap(input, &actual)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		data, _ := json.Marshal(input)
+		expected := embeddingParent{}
+		err = json.Unmarshal(data, &expected)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapTextUnmarshaler(t *testing.T) {
+	low := textLevel(1)
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		mode           Mode
+		expectedErr    bool
+		expectedStruct textStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name: "valid_values",
+			input: map[string]interface{}{
+				"ip": "127.0.0.1", "level": "high", "ptr": "low", "levels": map[string]interface{}{"low": float64(1)},
+			},
+			mode:        ModeFailOnFirstError,
+			expectedErr: false,
+			expectedStruct: textStruct{
+				IP: net.ParseIP("127.0.0.1"), Level: 2, Ptr: &low, Levels: map[textLevel]int{1: 1},
+			},
+			expectedMap: map[string]interface{}{
+				"ip": net.ParseIP("127.0.0.1"), "level": textLevel(2), "ptr": &low, "levels": map[textLevel]int{1: 1},
+			},
+		},
+		{
+			name:           "ModeFailOnFirstError_non_string_value",
+			input:          map[string]interface{}{"level": float64(2)},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: textStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name: "ModeFailOverToOriginalValue_invalid_values",
+			input: map[string]interface{}{
+				"ip": "foo", "level": "medium", "levels": map[string]interface{}{"medium": float64(1)}, "ptr": "low",
+			},
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: textStruct{Ptr: &low},
+			expectedMap: map[string]interface{}{
+				"ip": "foo", "level": "medium", "levels": map[string]interface{}{"medium": float64(1)}, "ptr": &low,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := textStruct{}
+			result, err := UnmarshalFromJSONMap(tt.input, &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+	t.Run("parse_error_path", func(t *testing.T) {
+		_, err := UnmarshalFromJSONMap(map[string]interface{}{"level": "medium"}, &textStruct{})
+		var parseError *ParseError
+		if !errors.As(err, &parseError) || parseError.Path != "level" || parseError.Err == nil {
+			t.Errorf("UnmarshalFromJSONMap() unexpected error %v", err)
+		}
+	})
+	t.Run("map_key_parse_error_path", func(t *testing.T) {
+		input := map[string]interface{}{"levels": map[string]interface{}{"medium": float64(1)}}
+		_, err := UnmarshalFromJSONMap(input, &textStruct{})
+		var parseError *ParseError
+		if !errors.As(err, &parseError) || parseError.Path != "levels.medium" || parseError.Err == nil {
+			t.Errorf("UnmarshalFromJSONMap() unexpected error %v", err)
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapMapKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		mode           Mode
+		expectedErr    bool
+		expectedStruct mapKeysStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name: "valid_keys",
+			input: map[string]interface{}{
+				"ints": map[string]interface{}{"-1": "a", "2": "b"}, "uints": map[string]interface{}{"255": "c"},
+			},
+			mode:        ModeFailOnFirstError,
+			expectedErr: false,
+			expectedStruct: mapKeysStruct{
+				Ints: map[int]string{-1: "a", 2: "b"}, Uints: map[uint8]string{255: "c"},
+			},
+			expectedMap: map[string]interface{}{
+				"ints": map[int]string{-1: "a", 2: "b"}, "uints": map[uint8]string{255: "c"},
+			},
+		},
+		{
+			name:           "ModeFailOnFirstError_overflowing_key",
+			input:          map[string]interface{}{"uints": map[string]interface{}{"256": "c"}},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: mapKeysStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOnFirstError_unsupported_key",
+			input:          map[string]interface{}{"floats": map[string]interface{}{"1.5": "a"}},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: mapKeysStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOverToOriginalValue_invalid_key",
+			input:          map[string]interface{}{"ints": map[string]interface{}{"1": "a", "b": "c"}},
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: mapKeysStruct{},
+			expectedMap:    map[string]interface{}{"ints": map[string]interface{}{"1": "a", "b": "c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mapKeysStruct{}
+			result, err := UnmarshalFromJSONMap(tt.input, &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapNumberMode(t *testing.T) {
+	input := map[string]interface{}{
+		"any":    []interface{}{json.Number("9007199254740993"), 1.5},
+		"amount": json.Number("2"),
+		"id":     json.Number("9007199254740993"),
+		"nested": map[string]interface{}{"a": []interface{}{float64(1000), json.Number("-0.5")}},
+	}
+	tests := []struct {
+		name           string
+		mode           NumberMode
+		expectedStruct numberModeStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name: "NumberModeFloat64",
+			mode: NumberModeFloat64,
+			expectedStruct: numberModeStruct{
+				Any:    []interface{}{json.Number("9007199254740993"), 1.5},
+				Amount: 2,
+				Extras: map[string]interface{}{
+					"id":     json.Number("9007199254740993"),
+					"nested": map[string]interface{}{"a": []interface{}{float64(1000), json.Number("-0.5")}},
+				},
+			},
+			expectedMap: map[string]interface{}{
+				"any": []interface{}{json.Number("9007199254740993"), 1.5}, "amount": float64(2),
+				"id":     json.Number("9007199254740993"),
+				"nested": map[string]interface{}{"a": []interface{}{float64(1000), json.Number("-0.5")}},
+			},
+		},
+		{
+			name: "NumberModeJSONNumber",
+			mode: NumberModeJSONNumber,
+			expectedStruct: numberModeStruct{
+				Any:    []interface{}{json.Number("9007199254740993"), json.Number("1.5")},
+				Amount: 2,
+				Extras: map[string]interface{}{
+					"id":     json.Number("9007199254740993"),
+					"nested": map[string]interface{}{"a": []interface{}{json.Number("1000"), json.Number("-0.5")}},
+				},
+			},
+			expectedMap: map[string]interface{}{
+				"any": []interface{}{json.Number("9007199254740993"), json.Number("1.5")}, "amount": float64(2),
+				"id":     json.Number("9007199254740993"),
+				"nested": map[string]interface{}{"a": []interface{}{json.Number("1000"), json.Number("-0.5")}},
+			},
+		},
+		{
+			name: "NumberModeInt64IfIntegral",
+			mode: NumberModeInt64IfIntegral,
+			expectedStruct: numberModeStruct{
+				Any:    []interface{}{int64(9007199254740993), 1.5},
+				Amount: 2,
+				Extras: map[string]interface{}{
+					"id": int64(9007199254740993), "nested": map[string]interface{}{"a": []interface{}{int64(1000), -0.5}},
+				},
+			},
+			expectedMap: map[string]interface{}{
+				"any": []interface{}{int64(9007199254740993), 1.5}, "amount": float64(2), "id": int64(9007199254740993),
+				"nested": map[string]interface{}{"a": []interface{}{int64(1000), -0.5}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := numberModeStruct{}
+			result, err := UnmarshalFromJSONMap(input, &s, WithNumberMode(tt.mode))
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapNumericConversion(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		mode           Mode
+		expectedErr    string
+		expectedStruct numericStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name: "valid_values",
+			input: map[string]interface{}{
+				"i8": float64(-128), "u": json.Number("0"), "i": json.Number("1e3"),
+				"i64": json.Number("9223372036854775807"), "u64": int64(7), "f32": 1.5,
+			},
+			mode: ModeFailOnFirstError,
+			expectedStruct: numericStruct{
+				I8: -128, U: 0, I: 1000, I64: 9223372036854775807, U64: 7, F32: 1.5,
+			},
+			expectedMap: map[string]interface{}{
+				"i8": int8(-128), "u": uint(0), "i": 1000, "i64": int64(9223372036854775807),
+				"u64": uint64(7), "f32": float32(1.5),
+			},
+		},
+		{
+			name:           "ModeFailOnFirstError_overflow",
+			input:          map[string]interface{}{"i8": float64(300)},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    "number 300 overflows int8",
+			expectedStruct: numericStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOnFirstError_fraction",
+			input:          map[string]interface{}{"i": json.Number("1.7")},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    "number 1.7 is not an integer, expected int",
+			expectedStruct: numericStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name: "ModeFailOverToOriginalValue_invalid_values",
+			input: map[string]interface{}{
+				"u": float64(-1), "i64": json.Number("9223372036854775808"), "f32": 1e39, "u64": float64(1),
+			},
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    "overflows",
+			expectedStruct: numericStruct{U64: 1},
+			expectedMap: map[string]interface{}{
+				"u": float64(-1), "i64": json.Number("9223372036854775808"), "f32": 1e39, "u64": uint64(1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := numericStruct{}
+			result, err := UnmarshalFromJSONMap(tt.input, &s, WithMode(tt.mode))
+			if (err != nil) != (tt.expectedErr != "") || (err != nil && !strings.Contains(err.Error(), tt.expectedErr)) {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+	t.Run("number_error", func(t *testing.T) {
+		errorTests := []struct {
+			input    map[string]interface{}
+			expected NumberError
+		}{
+			{
+				input:    map[string]interface{}{"i8": float64(128)},
+				expected: NumberError{Value: "128", Type: reflect.TypeOf(int8(0)), Reason: NumberOverflow},
+			},
+			{
+				input:    map[string]interface{}{"i": json.Number("1.7")},
+				expected: NumberError{Value: "1.7", Type: reflect.TypeOf(0), Reason: NumberFraction},
+			},
+		}
+		for _, tt := range errorTests {
+			_, err := UnmarshalFromJSONMap(tt.input, &numericStruct{})
+			var parseError *ParseError
+			var numberError *NumberError
+			if !errors.As(err, &parseError) || !errors.As(err, &numberError) {
+				t.Errorf("UnmarshalFromJSONMap() unexpected error %v", err)
+				continue
+			}
+			if parseError.Path != "i8" && parseError.Path != "i" {
+				t.Errorf("UnmarshalFromJSONMap() unexpected error path %s", parseError.Path)
+			}
+			if diff := deep.Equal(*numberError, tt.expected); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() error mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapMergeExisting(t *testing.T) {
+	input := map[string]interface{}{
+		"child":  map[string]interface{}{"a": "A"},
+		"ptr":    map[string]interface{}{"b": "B"},
+		"map":    map[string]interface{}{"y": float64(3), "z": float64(4)},
+		"number": float64(5),
+		"slice":  []interface{}{float64(9)},
+		"custom": "x",
+	}
+	t.Run("test_merge_existing", func(t *testing.T) {
+		actual := buildMergeParent()
+		ptr, number := actual.Ptr, actual.Number
+		_, err := UnmarshalFromJSONMap(input, actual)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := buildMergeParent()
+		expected.Child.A = "A"
+		expected.Ptr.B = "B"
+		expected.Map["y"], expected.Map["z"] = 3, 4
+		*expected.Number = 5
+		expected.Slice = []int{9}
+		expected.Custom.Calls = append(expected.Custom.Calls, "x")
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if actual.Ptr != ptr || actual.Number != number {
+			t.Errorf("UnmarshalFromJSONMap() did not reuse existing pointers")
+		}
+	})
+	t.Run("test_replace_existing", func(t *testing.T) {
+		actual := buildMergeParent()
+		_, err := UnmarshalFromJSONMap(input, actual, WithReplaceExisting(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		number := 5
+		expected := &mergeParent{
+			Child:  mergeChild{A: "A"},
+			Ptr:    &mergeChild{B: "B"},
+			Map:    map[string]int{"y": 3, "z": 4},
+			Number: &number,
+			Slice:  []int{9},
+			Custom: mergeCustom{Calls: []string{"x"}},
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_merge_fail_over_original_value", func(t *testing.T) {
+		actual := buildMergeParent()
+		failOverInput := map[string]interface{}{"map": map[string]interface{}{"y": float64(3), "z": "x"}}
+		result, err := UnmarshalFromJSONMap(failOverInput, actual, WithMode(ModeFailOverToOriginalValue))
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		if diff := deep.Equal(result, failOverInput); diff != nil {
+			t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapNullValues(t *testing.T) {
+	input := map[string]interface{}{
+		"ptr": nil, "map": nil, "slice": nil, "any": nil, "str": nil, "child": nil, "unknown": nil,
+	}
+	tests := []struct {
+		name        string
+		options     []UnmarshalOption
+		expectedMap map[string]interface{}
+	}{
+		{
+			name:    "null_results",
+			options: nil,
+			expectedMap: map[string]interface{}{
+				"ptr": nil, "map": nil, "slice": nil, "any": nil, "str": nil, "child": nil, "unknown": nil,
+			},
+		},
+		{
+			name:        "omit_null_results",
+			options:     []UnmarshalOption{WithOmitNullResults(true)},
+			expectedMap: map[string]interface{}{"unknown": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := buildNullStruct()
+			result, err := UnmarshalFromJSONMap(input, actual, tt.options...)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			expected := &nullStruct{Str: "str"}
+			if diff := deep.Equal(actual, expected); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapFieldSet(t *testing.T) {
+	t.Run("test_field_set", func(t *testing.T) {
+		input := map[string]interface{}{
+			"field":    "",
+			"child":    map[string]interface{}{"a": "x"},
+			"children": []interface{}{map[string]interface{}{"b": "y"}},
+			"map":      map[string]interface{}{"k": map[string]interface{}{"a": nil}},
+			"ptr":      nil,
+			"unknown":  map[string]interface{}{"a": float64(1)},
+		}
+		fieldSet := &FieldSet{}
+		_, err := UnmarshalFromJSONMap(input, &fieldSetParent{}, WithFieldSet(fieldSet))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := []string{"child", "child.a", "children", "children.b", "field", "map", "map.k.a", "ptr"}
+		if diff := deep.Equal(fieldSet.Paths(), expected); diff != nil {
+			t.Errorf("FieldSet.Paths() mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if !fieldSet.IsNull("ptr") || !fieldSet.IsNull("map.k.a") || fieldSet.IsNull("field") {
+			t.Errorf("FieldSet.IsNull() unexpected result")
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapArrayOverflow(t *testing.T) {
+	input := map[string]interface{}{
+		"arr":   []interface{}{float64(1), float64(2), float64(3), map[string]interface{}{"a": float64(4)}},
+		"field": "foo",
+	}
+	tests := []struct {
+		name           string
+		mode           Mode
+		policy         ArrayOverflowPolicy
+		expectedErr    bool
+		expectedStruct arrayOverflowStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:           "ArrayOverflowDrop",
+			mode:           ModeFailOnFirstError,
+			policy:         ArrayOverflowDrop,
+			expectedErr:    false,
+			expectedStruct: arrayOverflowStruct{Arr: [2]int{1, 2}, Field: "foo"},
+			expectedMap:    map[string]interface{}{"arr": [2]int{1, 2}, "field": "foo"},
+		},
+		{
+			name:           "ArrayOverflowError_ModeFailOnFirstError",
+			mode:           ModeFailOnFirstError,
+			policy:         ArrayOverflowError,
+			expectedErr:    true,
+			expectedStruct: arrayOverflowStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ArrayOverflowError_ModeFailOverToOriginalValue",
+			mode:           ModeFailOverToOriginalValue,
+			policy:         ArrayOverflowError,
+			expectedErr:    true,
+			expectedStruct: arrayOverflowStruct{Field: "foo"},
+			expectedMap:    map[string]interface{}{"arr": input["arr"], "field": "foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := arrayOverflowStruct{}
+			result, err := UnmarshalFromJSONMap(input, &s, WithMode(tt.mode), WithArrayOverflowPolicy(tt.policy))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if tt.mode == ModeFailOnFirstError && tt.expectedErr {
+				// map iteration order is random, thus fields decoded before the error may vary
+				return
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapBase64Bytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		mode           Mode
+		expectedErr    bool
+		expectedStruct bytesStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:           "base64_strings",
+			input:          map[string]interface{}{"bytes": "Zm9v", "named": "YmFy", "field": "foo"},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    false,
+			expectedStruct: bytesStruct{Bytes: []byte("foo"), Named: namedBytes("bar"), Field: "foo"},
+			expectedMap: map[string]interface{}{
+				"bytes": []byte("foo"),
+				"named": namedBytes("bar"),
+				"field": "foo",
+			},
+		},
+		{
+			name: "arrays",
+			input: map[string]interface{}{
+				"bytes": []interface{}{float64(102), float64(111), float64(111)},
+				"named": []interface{}{float64(98), float64(97), float64(114)},
+				"field": "foo",
+			},
+			mode:           ModeFailOnFirstError,
+			expectedErr:    false,
+			expectedStruct: bytesStruct{Bytes: []byte("foo"), Named: namedBytes("bar"), Field: "foo"},
+			expectedMap: map[string]interface{}{
+				"bytes": []byte("foo"),
+				"named": namedBytes("bar"),
+				"field": "foo",
+			},
+		},
+		{
+			name:           "invalid_base64_ModeFailOverToOriginalValue",
+			input:          map[string]interface{}{"bytes": "!!!", "field": "foo"},
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: bytesStruct{Field: "foo"},
+			expectedMap:    map[string]interface{}{"bytes": "!!!", "field": "foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bytesStruct{}
+			result, err := UnmarshalFromJSONMap(tt.input, &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapBigNumbers(t *testing.T) {
+	t.Run("valid_numbers", func(t *testing.T) {
+		input := map[string]interface{}{
+			"int":         json.Number("123456789012345678901234567890"),
+			"float":       json.Number("1.00000000000000000000000001"),
+			"int_value":   float64(1e21),
+			"decimal":     json.Number("12.50"),
+			"decimal_ptr": int64(-1),
+		}
+		s := bigNumbersStruct{}
+		result, err := UnmarshalFromJSONMap(input, &s)
+		if err != nil {
+			t.Fatalf("UnmarshalFromJSONMap() unexpected error = %v", err)
+		}
+		if s.Int == nil || s.Int.String() != "123456789012345678901234567890" {
+			t.Errorf("UnmarshalFromJSONMap() unexpected Int = %v", s.Int)
+		}
+		if s.Float == nil || s.Float.Text('f', 26) != "1.00000000000000000000000001" {
+			t.Errorf("UnmarshalFromJSONMap() unexpected Float = %v", s.Float)
+		}
+		if s.IntValue.String() != "1000000000000000000000" {
+			t.Errorf("UnmarshalFromJSONMap() unexpected IntValue = %v", s.IntValue.String())
+		}
+		if diff := deep.Equal(s.Decimal, decimal{Text: "12.50"}); diff != nil {
+			t.Errorf("UnmarshalFromJSONMap() Decimal mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if diff := deep.Equal(s.DecimalPtr, &decimal{Text: "-1"}); diff != nil {
+			t.Errorf("UnmarshalFromJSONMap() DecimalPtr mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if result["int"] != s.Int || result["float"] != s.Float {
+			t.Errorf("UnmarshalFromJSONMap() unexpected result = %v", result)
+		}
+	})
+	tests := []struct {
+		name        string
+		input       map[string]interface{}
+		mode        Mode
+		expectedErr bool
+		expectedMap map[string]interface{}
+	}{
+		{
+			name:        "null",
+			input:       map[string]interface{}{"int": nil, "decimal_ptr": nil},
+			mode:        ModeFailOnFirstError,
+			expectedErr: false,
+			expectedMap: map[string]interface{}{"int": nil, "decimal_ptr": nil},
+		},
+		{
+			name:        "fraction_into_big_int",
+			input:       map[string]interface{}{"int": 1.5},
+			mode:        ModeFailOnFirstError,
+			expectedErr: true,
+			expectedMap: nil,
+		},
+		{
+			name:        "exponent_into_big_int",
+			input:       map[string]interface{}{"int": json.Number("1e300000000")},
+			mode:        ModeFailOnFirstError,
+			expectedErr: true,
+			expectedMap: nil,
+		},
+		{
+			name:        "string_into_big_int",
+			input:       map[string]interface{}{"int": "1"},
+			mode:        ModeFailOnFirstError,
+			expectedErr: true,
+			expectedMap: nil,
+		},
+		{
+			name:        "invalid_ModeFailOverToOriginalValue",
+			input:       map[string]interface{}{"int": 1.5, "decimal": "1"},
+			mode:        ModeFailOverToOriginalValue,
+			expectedErr: true,
+			expectedMap: map[string]interface{}{"int": 1.5, "decimal": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bigNumbersStruct{}
+			result, err := UnmarshalFromJSONMap(tt.input, &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapResultContent(t *testing.T) {
+	input := map[string]interface{}{
+		"field": "a",
+		"child": map[string]interface{}{"field": "b", "extra": float64(2)},
+		"extra": float64(1),
+	}
+	fullStruct := resultContentStruct{Field: "a", Child: resultContentChild{Field: "b"}}
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		mode           Mode
+		content        ResultContent
+		nestedResults  bool
+		expectedErr    bool
+		expectedStruct resultContentStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:           "ResultAll",
+			input:          input,
+			mode:           ModeFailOnFirstError,
+			content:        ResultAll,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap:    map[string]interface{}{"field": "a", "child": fullStruct.Child, "extra": float64(1)},
+		},
+		{
+			name:           "ResultUnknownOnly",
+			input:          input,
+			mode:           ModeFailOnFirstError,
+			content:        ResultUnknownOnly,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap:    map[string]interface{}{"extra": float64(1)},
+		},
+		{
+			name:           "ResultKnownOnly",
+			input:          input,
+			mode:           ModeFailOnFirstError,
+			content:        ResultKnownOnly,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap:    map[string]interface{}{"field": "a", "child": fullStruct.Child},
+		},
+		{
+			name:           "ResultKnownOnly_nested_results",
+			input:          input,
+			mode:           ModeFailOnFirstError,
+			content:        ResultKnownOnly,
+			nestedResults:  true,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap: map[string]interface{}{
+				"field": "a",
+				"child": map[string]interface{}{"field": "b"},
+			},
+		},
+		{
+			name:           "ResultUnknownOnly_ModeFailOverToOriginalValue",
+			input:          map[string]interface{}{"field": float64(1), "extra": float64(1)},
+			mode:           ModeFailOverToOriginalValue,
+			content:        ResultUnknownOnly,
+			expectedErr:    true,
+			expectedStruct: resultContentStruct{},
+			expectedMap:    map[string]interface{}{"extra": float64(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := resultContentStruct{}
+			result, err := UnmarshalFromJSONMap(tt.input, &s, WithMode(tt.mode), WithResultContent(tt.content),
+				WithNestedResults(tt.nestedResults))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("UnmarshalFromJSONMap() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnmarshalFromJSONMapNativeResultTypes(t *testing.T) {
+	input := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(nativeData), &input); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, nestedResults := range []bool{false, true} {
+		t.Run(fmt.Sprintf("nested_results_%v", nestedResults), func(t *testing.T) {
+			s := nativeStruct{}
+			result, err := UnmarshalFromJSONMap(input, &s, WithNativeResultTypes(true), WithNestedResults(nestedResults))
+			if err != nil {
+				t.Fatalf("UnmarshalFromJSONMap() unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(result, input) {
+				t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%+v\n%+v", result, input)
+			}
+		})
+	}
+	t.Run("failover_containers", func(t *testing.T) {
+		s := struct {
+			List []int          `json:"l"`
+			Map  map[string]int `json:"m"`
+		}{}
+		input := map[string]interface{}{
+			"l": []interface{}{float64(1), "x", float64(3)},
+			"m": map[string]interface{}{"a": float64(1), "b": "x"},
+		}
+		result, err := UnmarshalFromJSONMap(input, &s, WithMode(ModeFailOverToOriginalValue), WithNativeResultTypes(true))
+		if err == nil {
+			t.Errorf("UnmarshalFromJSONMap() expected error")
+		}
+		if diff := deep.Equal(result, input); diff != nil {
+			t.Errorf("UnmarshalFromJSONMap() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+func TestUnmarshalFromJSONMapOrderedMapInput(t *testing.T) {
+	child := &OrderedMap{}
+	child.Set("field", "b")
+	child.Set("extra", float64(1))
+	unknown := &OrderedMap{}
+	unknown.Set("b", float64(2))
+	unknown.Set("a", float64(3))
+	input := map[string]interface{}{"field": "a", "child": child, "unknown": unknown}
+	p := extrasParent{}
+	result, err := UnmarshalFromJSONMap(input, &p, WithNumberMode(NumberModeInt64IfIntegral))
+	if err != nil {
+		t.Fatalf("UnmarshalFromJSONMap() unexpected error %v", err)
+	}
+	expected := extrasParent{
+		Field:  "a",
+		Child:  extrasChild{Field: "b", Extras: map[string]interface{}{"extra": int64(1)}},
+		Extras: map[string]interface{}{"unknown": result["unknown"]},
+	}
+	if diff := deep.Equal(p, expected); diff != nil {
+		t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+	}
+	converted, ok := result["unknown"].(*OrderedMap)
+	if !ok {
+		t.Fatalf("UnmarshalFromJSONMap() unexpected result %+v", result)
+	}
+	if diff := deep.Equal(converted.Keys(), []string{"b", "a"}); diff != nil {
+		t.Errorf("UnmarshalFromJSONMap() unexpected keys:\n%s", strings.Join(diff, "\n"))
+	}
+	if value, _ := converted.Get("a"); value != int64(3) {
+		t.Errorf("UnmarshalFromJSONMap() unexpected value %v", value)
+	}
+}
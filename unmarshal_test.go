@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"github.com/go-test/deep"
 	"github.com/mailru/easyjson/jlexer"
+	"math/big"
+	"net"
 	"reflect"
 	"strings"
 	"testing"
@@ -2131,7 +2133,7 @@ func TestUnmarshalInputVariations(t *testing.T) {
 					expectedMap[k] = v
 				}
 				structValue := reflectStructValue(actualStruct)
-				for name, refInfo := range mapStructFields(actualStruct) {
+				for name, refInfo := range mapStructFields(actualStruct).fields {
 					field := refInfo.field(structValue)
 					expectedMap[name] = field.Interface()
 				}
@@ -2494,3 +2496,1806 @@ func normalizeMapTypes(m map[string]interface{}) {
 		}
 	}
 }
+
+type nestedParent struct {
+	Child    nestedChild            `json:"child"`
+	ChildPtr *nestedChild           `json:"child_ptr"`
+	Children []nestedChild          `json:"children"`
+	Array    [2]*nestedChild        `json:"array"`
+	Map      map[string]nestedChild `json:"map"`
+	Numbers  []int                  `json:"numbers"`
+}
+
+type nestedChild struct {
+	Field string `json:"field"`
+}
+
+func TestNestedResults(t *testing.T) {
+	data := []byte(`{"child":{"field":"a","extra":1},"child_ptr":{"field":"b","extra":[2]},
+		"children":[{"field":"c","extra":{"x":3}}],"array":[{"field":"d","extra":true}],
+		"map":{"key":{"field":"e","extra":"5"}},"numbers":[1,2],"extra":6}`)
+	t.Run("test_nested_unknown_fields_skipped_by_default", func(t *testing.T) {
+		p := nestedParent{}
+		result, err := Unmarshal(data, &p)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if p.Child.Field != "a" || p.ChildPtr.Field != "b" || p.Children[0].Field != "c" ||
+			p.Array[0].Field != "d" || p.Map["key"].Field != "e" {
+			t.Errorf("missing nested values in struct %+v", p)
+		}
+		if diff := deep.Equal(result["child"], nestedChild{Field: "a"}); diff != nil {
+			t.Errorf("unexpected nested value in map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_nested_results", func(t *testing.T) {
+		p := nestedParent{}
+		result, err := Unmarshal(data, &p, WithNestedResults(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if p.Child.Field != "a" || p.ChildPtr.Field != "b" || p.Children[0].Field != "c" ||
+			p.Array[0].Field != "d" || p.Map["key"].Field != "e" {
+			t.Errorf("missing nested values in struct %+v", p)
+		}
+		expected := map[string]interface{}{
+			"child":     map[string]interface{}{"field": "a", "extra": float64(1)},
+			"child_ptr": map[string]interface{}{"field": "b", "extra": []interface{}{float64(2)}},
+			"children": []interface{}{
+				map[string]interface{}{"field": "c", "extra": map[string]interface{}{"x": float64(3)}},
+			},
+			"array": []interface{}{map[string]interface{}{"field": "d", "extra": true}},
+			"map": map[string]interface{}{
+				"key": map[string]interface{}{"field": "e", "extra": "5"},
+			},
+			"numbers": []int{1, 2},
+			"extra":   float64(6),
+		}
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_nested_results_fail_over", func(t *testing.T) {
+		p := nestedParent{}
+		result, err := Unmarshal([]byte(`{"children":[{"field":1,"extra":2}]}`), &p,
+			WithNestedResults(true), WithMode(ModeFailOverToOriginalValue))
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		expected := map[string]interface{}{
+			"children": []interface{}{map[string]interface{}{"field": float64(1), "extra": float64(2)}},
+		}
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+type extrasParent struct {
+	Field    string                 `json:"field"`
+	Child    extrasChild            `json:"child"`
+	Children []*extrasChild         `json:"children"`
+	Extras   map[string]interface{} `json:"-" marshmallow:",extras"`
+}
+
+type extrasChild struct {
+	Field  string                 `json:"field"`
+	Extras map[string]interface{} `json:"-" marshmallow:",extras"`
+}
+
+func TestExtras(t *testing.T) {
+	data := []byte(`{"field":"a","extra":1,"child":{"field":"b","extra":[2]},"children":[{"field":"c","extra":{"x":3}},{"field":"d"}]}`)
+	t.Run("test_extras_populated", func(t *testing.T) {
+		p := extrasParent{}
+		result, err := Unmarshal(data, &p)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := extrasParent{
+			Field:  "a",
+			Extras: map[string]interface{}{"extra": float64(1)},
+			Child: extrasChild{
+				Field:  "b",
+				Extras: map[string]interface{}{"extra": []interface{}{float64(2)}},
+			},
+			Children: []*extrasChild{
+				{Field: "c", Extras: map[string]interface{}{"extra": map[string]interface{}{"x": float64(3)}}},
+				{Field: "d"},
+			},
+		}
+		if diff := deep.Equal(p, expected); diff != nil {
+			t.Errorf("unexpected struct value:\n%s", strings.Join(diff, "\n"))
+		}
+		if len(result) != 4 || result["extra"] != float64(1) {
+			t.Errorf("unexpected result map %+v", result)
+		}
+	})
+	t.Run("test_extras_skip_populate_struct", func(t *testing.T) {
+		p := extrasParent{}
+		_, err := Unmarshal(data, &p, WithSkipPopulateStruct(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if p.Extras != nil {
+			t.Errorf("unexpected extras %+v", p.Extras)
+		}
+	})
+}
+
+type untaggedStruct struct {
+	Name     string
+	Age      int    `json:",omitempty"`
+	Renamed  bool   `json:"renamed"`
+	Ignored  string `json:"-"`
+	internal string
+}
+
+func TestUntaggedFields(t *testing.T) {
+	t.Run("test_untagged_fields_mapped_by_name", func(t *testing.T) {
+		s := untaggedStruct{}
+		result, err := Unmarshal([]byte(`{"Name":"foo","Age":12,"renamed":true,"Ignored":"a","internal":"b"}`), &s)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := untaggedStruct{Name: "foo", Age: 12, Renamed: true}
+		if diff := deep.Equal(s, expected); diff != nil {
+			t.Errorf("unexpected struct value:\n%s", strings.Join(diff, "\n"))
+		}
+		expectedMap := map[string]interface{}{"Name": "foo", "Age": 12, "renamed": true, "Ignored": "a", "internal": "b"}
+		if diff := deep.Equal(result, expectedMap); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+type caseInsensitiveStruct struct {
+	UserID string `json:"userId"`
+	UserId string `json:"userid"`
+	Name   string
+}
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+	t.Run("test_case_sensitive_by_default", func(t *testing.T) {
+		s := caseInsensitiveStruct{}
+		result, err := Unmarshal([]byte(`{"USERID":"a","name":"b"}`), &s)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if s != (caseInsensitiveStruct{}) {
+			t.Errorf("unexpected struct value %+v", s)
+		}
+		if len(result) != 2 || result["USERID"] != "a" || result["name"] != "b" {
+			t.Errorf("unexpected result map %+v", result)
+		}
+	})
+	t.Run("test_case_insensitive", func(t *testing.T) {
+		s := caseInsensitiveStruct{}
+		result, err := Unmarshal([]byte(`{"USERID":"a","userid":"b","NAME":"c"}`), &s, WithCaseInsensitiveKeys(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := caseInsensitiveStruct{UserID: "a", UserId: "b", Name: "c"}
+		if s != expected {
+			t.Errorf("unexpected struct value %+v", s)
+		}
+		if len(result) != 3 || result["USERID"] != "a" || result["userid"] != "b" || result["NAME"] != "c" {
+			t.Errorf("unexpected result map %+v", result)
+		}
+	})
+	t.Run("test_unicode_folding", func(t *testing.T) {
+		s := struct {
+			Field string `json:"kelvin"`
+		}{}
+		_, err := Unmarshal([]byte(`{"\u212aELVIN":"a"}`), &s, WithCaseInsensitiveKeys(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if s.Field != "a" {
+			t.Errorf("unexpected struct value %+v", s)
+		}
+	})
+	t.Run("test_lookup_does_not_allocate", func(t *testing.T) {
+		info := mapStructFields(&caseInsensitiveStruct{})
+		allocs := testing.AllocsPerRun(100, func() {
+			info.lookup("NAME", true)
+		})
+		if allocs != 0 {
+			t.Errorf("unexpected allocations %v", allocs)
+		}
+	})
+}
+
+type quotedStruct struct {
+	ID      int64   `json:"id,string"`
+	Ptr     *uint8  `json:"ptr,string"`
+	Flag    bool    `json:"flag,string"`
+	Amount  float64 `json:"amount,string"`
+	Name    string  `json:"name,string"`
+	Ignored []int   `json:"ignored,string"`
+}
+
+func TestQuotedFields(t *testing.T) {
+	ptr := uint8(7)
+	tests := []struct {
+		name           string
+		data           string
+		mode           Mode
+		expectedErr    bool
+		expectedStruct quotedStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:        "valid_values",
+			data:        `{"id":"9007199254740993","ptr":"7","flag":"true","amount":"1.5","name":"\"foo\"","ignored":[1]}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: false,
+			expectedStruct: quotedStruct{
+				ID: 9007199254740993, Ptr: &ptr, Flag: true, Amount: 1.5, Name: "foo", Ignored: []int{1},
+			},
+			expectedMap: map[string]interface{}{
+				"id": int64(9007199254740993), "ptr": &ptr, "flag": true, "amount": 1.5, "name": "foo", "ignored": []int{1},
+			},
+		},
+		{
+			name:           "null_values",
+			data:           `{"id":null,"ptr":"null"}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    false,
+			expectedStruct: quotedStruct{},
+			expectedMap:    map[string]interface{}{"id": nil, "ptr": nil},
+		},
+		{
+			name:           "ModeFailOnFirstError_unquoted_value",
+			data:           `{"id":12}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: quotedStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOverToOriginalValue_invalid_values",
+			data:           `{"id":12,"amount":"0x12","flag":"yes","name":"foo"}`,
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: quotedStruct{},
+			expectedMap:    map[string]interface{}{"id": float64(12), "amount": "0x12", "flag": "yes", "name": "foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := quotedStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type embeddingParent struct {
+	*EmbeddedPtr
+	embeddedValue
+	Tagged   embeddedValue `json:"tagged"`
+	Shadowed string        `json:"shadowed"`
+	embeddedA
+	embeddedB
+}
+
+type EmbeddedPtr struct {
+	PtrField string `json:"ptr_field"`
+	Shadowed string `json:"shadowed"`
+}
+
+type embeddedPtr struct {
+	PtrField string `json:"ptr_field"`
+}
+
+type embeddedValue struct {
+	ValueField string `json:"value_field"`
+}
+
+type embeddedA struct {
+	Ambiguous  string
+	TaggedWins string `json:"TaggedWins"`
+}
+
+type embeddedB struct {
+	Ambiguous  string
+	TaggedWins string
+}
+
+func TestEmbeddingRules(t *testing.T) {
+	data := []byte(`{"ptr_field":"a","value_field":"b","tagged":{"value_field":"c"},"shadowed":"d",
+		"Ambiguous":"e","TaggedWins":"f"}`)
+	t.Run("test_embedding_rules_match_json", func(t *testing.T) {
+		actual := embeddingParent{}
+		result, err := Unmarshal(data, &actual)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := embeddingParent{}
+		err = json.Unmarshal(data, &expected)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if actual.EmbeddedPtr == nil || actual.PtrField != "a" || actual.EmbeddedPtr.Shadowed != "" ||
+			actual.Tagged.ValueField != "c" || actual.embeddedA.TaggedWins != "f" {
+			t.Errorf("unexpected struct value %+v", actual)
+		}
+		if len(result) != 6 {
+			t.Errorf("unexpected result map %+v", result)
+		}
+	})
+	t.Run("test_unexported_embedded_pointer_not_allocated", func(t *testing.T) {
+		v := struct {
+			*embeddedPtr
+			Field string `json:"field"`
+		}{}
+		_, err := Unmarshal([]byte(`{"ptr_field":"a","field":"b"}`), &v)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if v.embeddedPtr != nil || v.Field != "b" {
+			t.Errorf("unexpected struct value %+v", v)
+		}
+	})
+}
+
+type textStruct struct {
+	IP     net.IP            `json:"ip"`
+	Level  textLevel         `json:"level"`
+	Ptr    *textLevel        `json:"ptr"`
+	Levels map[textLevel]int `json:"levels"`
+}
+
+type textLevel int
+
+func (l *textLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("invalid level %q", text)
+	}
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	low := textLevel(1)
+	tests := []struct {
+		name           string
+		data           string
+		mode           Mode
+		expectedErr    bool
+		expectedStruct textStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:        "valid_values",
+			data:        `{"ip":"127.0.0.1","level":"high","ptr":"low","levels":{"low":1,"high":2}}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: false,
+			expectedStruct: textStruct{
+				IP: net.ParseIP("127.0.0.1"), Level: 2, Ptr: &low, Levels: map[textLevel]int{1: 1, 2: 2},
+			},
+			expectedMap: map[string]interface{}{
+				"ip": net.ParseIP("127.0.0.1"), "level": textLevel(2), "ptr": &low, "levels": map[textLevel]int{1: 1, 2: 2},
+			},
+		},
+		{
+			name:           "null_values",
+			data:           `{"ip":null,"ptr":null}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    false,
+			expectedStruct: textStruct{},
+			expectedMap:    map[string]interface{}{"ip": nil, "ptr": nil},
+		},
+		{
+			name:           "ModeFailOnFirstError_non_string_value",
+			data:           `{"level":2}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: textStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOverToOriginalValue_invalid_values",
+			data:           `{"ip":"foo","level":"medium","levels":{"medium":1},"ptr":"low"}`,
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: textStruct{Ptr: &low},
+			expectedMap: map[string]interface{}{
+				"ip": "foo", "level": "medium", "levels": map[string]interface{}{"medium": float64(1)}, "ptr": &low,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := textStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type mapKeysStruct struct {
+	Ints     map[int]string         `json:"ints"`
+	Uints    map[uint8]string       `json:"uints"`
+	Floats   map[float64]string     `json:"floats"`
+	Children map[int64]*nestedChild `json:"children"`
+}
+
+func TestMapKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		mode           Mode
+		expectedErr    bool
+		expectedStruct mapKeysStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:        "valid_keys",
+			data:        `{"ints":{"-1":"a","2":"b"},"uints":{"255":"c"}}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: false,
+			expectedStruct: mapKeysStruct{
+				Ints: map[int]string{-1: "a", 2: "b"}, Uints: map[uint8]string{255: "c"},
+			},
+			expectedMap: map[string]interface{}{
+				"ints": map[int]string{-1: "a", 2: "b"}, "uints": map[uint8]string{255: "c"},
+			},
+		},
+		{
+			name:           "ModeFailOnFirstError_overflowing_key",
+			data:           `{"uints":{"256":"c"}}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: mapKeysStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOnFirstError_unsupported_key",
+			data:           `{"floats":{"1.5":"a"}}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: mapKeysStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOverToOriginalValue_invalid_key",
+			data:           `{"ints":{"1":"a","b":"c","3":"d"}}`,
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: mapKeysStruct{},
+			expectedMap: map[string]interface{}{
+				"ints": map[string]interface{}{"1": "a", "b": "c", "3": "d"},
+			},
+		},
+		{
+			name:           "ModeFailOverToOriginalValue_invalid_value",
+			data:           `{"ints":{"1":"a","2":3}}`,
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: mapKeysStruct{},
+			expectedMap: map[string]interface{}{
+				"ints": map[string]interface{}{"1": "a", "2": float64(3)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mapKeysStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+	t.Run("test_nested_results_keys", func(t *testing.T) {
+		s := mapKeysStruct{}
+		result, err := Unmarshal([]byte(`{"children":{"9007199254740993":{"field":"a"}}}`), &s, WithNestedResults(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := map[string]interface{}{
+			"children": map[string]interface{}{"9007199254740993": map[string]interface{}{"field": "a"}},
+		}
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if s.Children[9007199254740993] == nil || s.Children[9007199254740993].Field != "a" {
+			t.Errorf("Unmarshal() unexpected children %v", s.Children)
+		}
+	})
+}
+
+type numberModeStruct struct {
+	Any    interface{}            `json:"any"`
+	Amount float64                `json:"amount"`
+	Extras map[string]interface{} `json:"-" marshmallow:",extras"`
+}
+
+func TestNumberMode(t *testing.T) {
+	data := `{"any":[9007199254740993,1.5],"amount":2,"id":9007199254740993,"nested":{"a":[1e3,-0.5]},"s":"1"}`
+	tests := []struct {
+		name           string
+		mode           NumberMode
+		expectedStruct numberModeStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name: "NumberModeFloat64",
+			mode: NumberModeFloat64,
+			expectedStruct: numberModeStruct{
+				Any:    []interface{}{float64(9007199254740992), 1.5},
+				Amount: 2,
+				Extras: map[string]interface{}{
+					"id": float64(9007199254740992), "nested": map[string]interface{}{"a": []interface{}{float64(1000), -0.5}}, "s": "1",
+				},
+			},
+			expectedMap: map[string]interface{}{
+				"any": []interface{}{float64(9007199254740992), 1.5}, "amount": float64(2), "id": float64(9007199254740992),
+				"nested": map[string]interface{}{"a": []interface{}{float64(1000), -0.5}}, "s": "1",
+			},
+		},
+		{
+			name: "NumberModeJSONNumber",
+			mode: NumberModeJSONNumber,
+			expectedStruct: numberModeStruct{
+				Any:    []interface{}{json.Number("9007199254740993"), json.Number("1.5")},
+				Amount: 2,
+				Extras: map[string]interface{}{
+					"id":     json.Number("9007199254740993"),
+					"nested": map[string]interface{}{"a": []interface{}{json.Number("1e3"), json.Number("-0.5")}},
+					"s":      "1",
+				},
+			},
+			expectedMap: map[string]interface{}{
+				"any": []interface{}{json.Number("9007199254740993"), json.Number("1.5")}, "amount": float64(2),
+				"id":     json.Number("9007199254740993"),
+				"nested": map[string]interface{}{"a": []interface{}{json.Number("1e3"), json.Number("-0.5")}}, "s": "1",
+			},
+		},
+		{
+			name: "NumberModeInt64IfIntegral",
+			mode: NumberModeInt64IfIntegral,
+			expectedStruct: numberModeStruct{
+				Any:    []interface{}{int64(9007199254740993), 1.5},
+				Amount: 2,
+				Extras: map[string]interface{}{
+					"id": int64(9007199254740993), "nested": map[string]interface{}{"a": []interface{}{int64(1000), -0.5}}, "s": "1",
+				},
+			},
+			expectedMap: map[string]interface{}{
+				"any": []interface{}{int64(9007199254740993), 1.5}, "amount": float64(2), "id": int64(9007199254740993),
+				"nested": map[string]interface{}{"a": []interface{}{int64(1000), -0.5}}, "s": "1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := numberModeStruct{}
+			result, err := Unmarshal([]byte(data), &s, WithNumberMode(tt.mode))
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type numericStruct struct {
+	I8  int8    `json:"i8"`
+	U   uint    `json:"u"`
+	I   int     `json:"i"`
+	I64 int64   `json:"i64"`
+	U64 uint64  `json:"u64"`
+	F32 float32 `json:"f32"`
+}
+
+func TestNumericConversion(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		mode           Mode
+		numberMode     NumberMode
+		expectedErr    string
+		expectedStruct numericStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name: "valid_values",
+			data: `{"i8":-128,"u":0,"i":1e3,"i64":9223372036854775807,"u64":18446744073709551615,"f32":1.5}`,
+			mode: ModeFailOnFirstError,
+			expectedStruct: numericStruct{
+				I8: -128, U: 0, I: 1000, I64: 9223372036854775807, U64: 18446744073709551615, F32: 1.5,
+			},
+			expectedMap: map[string]interface{}{
+				"i8": int8(-128), "u": uint(0), "i": 1000, "i64": int64(9223372036854775807),
+				"u64": uint64(18446744073709551615), "f32": float32(1.5),
+			},
+		},
+		{
+			name:           "ModeFailOnFirstError_overflow",
+			data:           `{"i8":300}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    "number 300 overflows int8",
+			expectedStruct: numericStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOnFirstError_negative_unsigned",
+			data:           `{"u":-1}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    "number -1 overflows uint",
+			expectedStruct: numericStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOnFirstError_fraction",
+			data:           `{"i":1.7}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    "number 1.7 is not an integer, expected int",
+			expectedStruct: numericStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ModeFailOverToOriginalValue_invalid_values",
+			data:           `{"i8":300,"u":-1,"i":1.7,"i64":9223372036854775808,"f32":1e39,"u64":1}`,
+			mode:           ModeFailOverToOriginalValue,
+			numberMode:     NumberModeJSONNumber,
+			expectedErr:    "number 300 overflows int8",
+			expectedStruct: numericStruct{U64: 1},
+			expectedMap: map[string]interface{}{
+				"i8": json.Number("300"), "u": json.Number("-1"), "i": json.Number("1.7"),
+				"i64": json.Number("9223372036854775808"), "f32": json.Number("1e39"), "u64": uint64(1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := numericStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode), WithNumberMode(tt.numberMode))
+			if (err != nil) != (tt.expectedErr != "") || (err != nil && !strings.Contains(err.Error(), tt.expectedErr)) {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type mergeParent struct {
+	Child  mergeChild     `json:"child"`
+	Ptr    *mergeChild    `json:"ptr"`
+	Map    map[string]int `json:"map"`
+	Number *int           `json:"number"`
+	Slice  []int          `json:"slice"`
+	Custom mergeCustom    `json:"custom"`
+}
+
+type mergeChild struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+type mergeCustom struct {
+	Calls []string
+}
+
+func (c *mergeCustom) UnmarshalJSON(data []byte) error {
+	c.Calls = append(c.Calls, string(data))
+	return nil
+}
+
+func (c *mergeCustom) UnmarshalJSONFromMap(data interface{}) error {
+	c.Calls = append(c.Calls, fmt.Sprint(data))
+	return nil
+}
+
+func buildMergeParent() *mergeParent {
+	number := 1
+	return &mergeParent{
+		Child:  mergeChild{A: "a", B: "b"},
+		Ptr:    &mergeChild{A: "a", B: "b"},
+		Map:    map[string]int{"x": 1, "y": 2},
+		Number: &number,
+		Slice:  []int{1, 2, 3},
+		Custom: mergeCustom{Calls: []string{"first"}},
+	}
+}
+
+func TestMergeExisting(t *testing.T) {
+	data := []byte(`{"child":{"a":"A"},"ptr":{"b":"B"},"map":{"y":3,"z":4},"number":5,"slice":[9],"custom":"x"}`)
+	t.Run("test_merge_matches_json", func(t *testing.T) {
+		actual := buildMergeParent()
+		ptr, number := actual.Ptr, actual.Number
+		_, err := Unmarshal(data, actual)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := buildMergeParent()
+		err = json.Unmarshal(data, expected)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if actual.Ptr != ptr || actual.Number != number {
+			t.Errorf("Unmarshal() did not reuse existing pointers")
+		}
+	})
+	t.Run("test_replace_existing", func(t *testing.T) {
+		actual := buildMergeParent()
+		ptr := actual.Ptr
+		_, err := Unmarshal(data, actual, WithReplaceExisting(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		number := 5
+		expected := &mergeParent{
+			Child:  mergeChild{A: "A"},
+			Ptr:    &mergeChild{B: "B"},
+			Map:    map[string]int{"y": 3, "z": 4},
+			Number: &number,
+			Slice:  []int{9},
+			Custom: mergeCustom{Calls: []string{`"x"`}},
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if actual.Ptr == ptr {
+			t.Errorf("Unmarshal() reused an existing pointer")
+		}
+	})
+	t.Run("test_merge_fail_over_original_value", func(t *testing.T) {
+		actual := buildMergeParent()
+		result, err := Unmarshal([]byte(`{"map":{"y":3,"z":"x"}}`), actual, WithMode(ModeFailOverToOriginalValue))
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		expected := map[string]interface{}{"map": map[string]interface{}{"y": 3, "z": "x"}}
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+}
+
+type nullStruct struct {
+	Ptr   *int           `json:"ptr"`
+	Map   map[string]int `json:"map"`
+	Slice []int          `json:"slice"`
+	Any   interface{}    `json:"any"`
+	Str   string         `json:"str"`
+	Child *mergeChild    `json:"child"`
+}
+
+func buildNullStruct() *nullStruct {
+	number := 1
+	return &nullStruct{
+		Ptr: &number, Map: map[string]int{"a": 1}, Slice: []int{1}, Any: "any", Str: "str", Child: &mergeChild{A: "a"},
+	}
+}
+
+func TestNullValues(t *testing.T) {
+	data := []byte(`{"ptr":null,"map":null,"slice":null,"any":null,"str":null,"child":null,"unknown":null}`)
+	tests := []struct {
+		name        string
+		options     []UnmarshalOption
+		expectedMap map[string]interface{}
+	}{
+		{
+			name:    "null_results",
+			options: nil,
+			expectedMap: map[string]interface{}{
+				"ptr": nil, "map": nil, "slice": nil, "any": nil, "str": nil, "child": nil, "unknown": nil,
+			},
+		},
+		{
+			name:        "omit_null_results",
+			options:     []UnmarshalOption{WithOmitNullResults(true)},
+			expectedMap: map[string]interface{}{"unknown": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := buildNullStruct()
+			result, err := Unmarshal(data, actual, tt.options...)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			expected := buildNullStruct()
+			err = json.Unmarshal(data, expected)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if diff := deep.Equal(actual, expected); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type fieldSetParent struct {
+	Field    string                `json:"field"`
+	Child    *mergeChild           `json:"child"`
+	Children []mergeChild          `json:"children"`
+	Map      map[string]mergeChild `json:"map"`
+	Ptr      *int                  `json:"ptr"`
+}
+
+func TestFieldSet(t *testing.T) {
+	t.Run("test_field_set", func(t *testing.T) {
+		data := `{"field":"","child":{"a":"x"},"children":[{"b":"y"}],"map":{"k":{"a":null}},"ptr":null,"unknown":{"a":1}}`
+		fieldSet := &FieldSet{}
+		_, err := Unmarshal([]byte(data), &fieldSetParent{}, WithFieldSet(fieldSet))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := []string{"child", "child.a", "children", "children.b", "field", "map", "map.k.a", "ptr"}
+		if diff := deep.Equal(fieldSet.Paths(), expected); diff != nil {
+			t.Errorf("FieldSet.Paths() mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if !fieldSet.IsNull("ptr") || !fieldSet.IsNull("map.k.a") || fieldSet.IsNull("field") {
+			t.Errorf("FieldSet.IsNull() unexpected result")
+		}
+		if fieldSet.Has("child.b") || fieldSet.Has("unknown") || !fieldSet.Has("field") {
+			t.Errorf("FieldSet.Has() unexpected result")
+		}
+	})
+}
+
+type arrayOverflowStruct struct {
+	Arr   [2]int `json:"arr"`
+	Field string `json:"field"`
+}
+
+func TestArrayOverflow(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		mode           Mode
+		policy         ArrayOverflowPolicy
+		expectedErr    bool
+		expectedStruct arrayOverflowStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:           "ArrayOverflowDrop",
+			data:           `{"arr":[1,2,3,[4]],"field":"foo"}`,
+			mode:           ModeFailOnFirstError,
+			policy:         ArrayOverflowDrop,
+			expectedErr:    false,
+			expectedStruct: arrayOverflowStruct{Arr: [2]int{1, 2}, Field: "foo"},
+			expectedMap:    map[string]interface{}{"arr": [2]int{1, 2}, "field": "foo"},
+		},
+		{
+			name:           "ArrayOverflowError_ModeFailOnFirstError",
+			data:           `{"arr":[1,2,3],"field":"foo"}`,
+			mode:           ModeFailOnFirstError,
+			policy:         ArrayOverflowError,
+			expectedErr:    true,
+			expectedStruct: arrayOverflowStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "ArrayOverflowError_ModeAllowMultipleErrors",
+			data:           `{"arr":[1,2,3,[4]],"field":"foo"}`,
+			mode:           ModeAllowMultipleErrors,
+			policy:         ArrayOverflowError,
+			expectedErr:    true,
+			expectedStruct: arrayOverflowStruct{Field: "foo"},
+			expectedMap:    map[string]interface{}{"arr": nil, "field": "foo"},
+		},
+		{
+			name:           "ArrayOverflowError_ModeFailOverToOriginalValue",
+			data:           `{"arr":[1,2,3,{"a":4}],"field":"foo"}`,
+			mode:           ModeFailOverToOriginalValue,
+			policy:         ArrayOverflowError,
+			expectedErr:    true,
+			expectedStruct: arrayOverflowStruct{Field: "foo"},
+			expectedMap: map[string]interface{}{
+				"arr":   []interface{}{1, 2, float64(3), map[string]interface{}{"a": float64(4)}},
+				"field": "foo",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := arrayOverflowStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode), WithArrayOverflowPolicy(tt.policy))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type namedBytes []byte
+
+type bytesStruct struct {
+	Bytes []byte     `json:"bytes"`
+	Named namedBytes `json:"named"`
+	Field string     `json:"field"`
+}
+
+func TestBase64Bytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		mode           Mode
+		expectedErr    bool
+		expectedStruct bytesStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:           "base64_strings",
+			data:           `{"bytes":"Zm9v","named":"YmFy","field":"foo"}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    false,
+			expectedStruct: bytesStruct{Bytes: []byte("foo"), Named: namedBytes("bar"), Field: "foo"},
+			expectedMap: map[string]interface{}{
+				"bytes": []byte("foo"),
+				"named": namedBytes("bar"),
+				"field": "foo",
+			},
+		},
+		{
+			name:           "arrays",
+			data:           `{"bytes":[102,111,111],"named":[98,97,114],"field":"foo"}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    false,
+			expectedStruct: bytesStruct{Bytes: []byte("foo"), Named: namedBytes("bar"), Field: "foo"},
+			expectedMap: map[string]interface{}{
+				"bytes": []byte("foo"),
+				"named": namedBytes("bar"),
+				"field": "foo",
+			},
+		},
+		{
+			name:           "invalid_base64_ModeFailOnFirstError",
+			data:           `{"bytes":"!!!","field":"foo"}`,
+			mode:           ModeFailOnFirstError,
+			expectedErr:    true,
+			expectedStruct: bytesStruct{},
+			expectedMap:    nil,
+		},
+		{
+			name:           "invalid_base64_ModeFailOverToOriginalValue",
+			data:           `{"bytes":"!!!","field":"foo"}`,
+			mode:           ModeFailOverToOriginalValue,
+			expectedErr:    true,
+			expectedStruct: bytesStruct{Field: "foo"},
+			expectedMap:    map[string]interface{}{"bytes": "!!!", "field": "foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bytesStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type duplicateKeysChild struct {
+	B int `json:"b"`
+}
+
+type duplicateKeysStruct struct {
+	A     string             `json:"a"`
+	Child duplicateKeysChild `json:"child"`
+	M     map[string]int     `json:"m"`
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	data := `{"a":"1","a":"2","child":{"b":1,"b":2},"m":{"x":1,"x":2},"u1":{"u":1,"u":2},"u2":1,"u2":2}`
+	firstStruct := duplicateKeysStruct{A: "1", Child: duplicateKeysChild{B: 1}, M: map[string]int{"x": 1}}
+	firstMap := map[string]interface{}{
+		"a":     "1",
+		"child": duplicateKeysChild{B: 1},
+		"m":     map[string]int{"x": 1},
+		"u1":    map[string]interface{}{"u": float64(1)},
+		"u2":    float64(1),
+	}
+	tests := []struct {
+		name            string
+		data            string
+		mode            Mode
+		policy          DuplicateKeyPolicy
+		caseInsensitive bool
+		expectedErr     bool
+		expectedStruct  duplicateKeysStruct
+		expectedMap     map[string]interface{}
+	}{
+		{
+			name:           "DuplicateLast",
+			data:           data,
+			mode:           ModeFailOnFirstError,
+			policy:         DuplicateLast,
+			expectedErr:    false,
+			expectedStruct: duplicateKeysStruct{A: "2", Child: duplicateKeysChild{B: 2}, M: map[string]int{"x": 2}},
+			expectedMap: map[string]interface{}{
+				"a":     "2",
+				"child": duplicateKeysChild{B: 2},
+				"m":     map[string]int{"x": 2},
+				"u1":    map[string]interface{}{"u": float64(2)},
+				"u2":    float64(2),
+			},
+		},
+		{
+			name:           "DuplicateFirst",
+			data:           data,
+			mode:           ModeFailOnFirstError,
+			policy:         DuplicateFirst,
+			expectedErr:    false,
+			expectedStruct: firstStruct,
+			expectedMap:    firstMap,
+		},
+		{
+			name:            "DuplicateFirst_case_insensitive",
+			data:            `{"a":"1","A":"2"}`,
+			mode:            ModeFailOnFirstError,
+			policy:          DuplicateFirst,
+			caseInsensitive: true,
+			expectedErr:     false,
+			expectedStruct:  duplicateKeysStruct{A: "1"},
+			expectedMap:     map[string]interface{}{"a": "1"},
+		},
+		{
+			name:           "DuplicateError_ModeFailOnFirstError",
+			data:           data,
+			mode:           ModeFailOnFirstError,
+			policy:         DuplicateError,
+			expectedErr:    true,
+			expectedStruct: duplicateKeysStruct{A: "1"},
+			expectedMap:    nil,
+		},
+		{
+			name:           "DuplicateError_ModeAllowMultipleErrors",
+			data:           data,
+			mode:           ModeAllowMultipleErrors,
+			policy:         DuplicateError,
+			expectedErr:    true,
+			expectedStruct: firstStruct,
+			expectedMap:    firstMap,
+		},
+		{
+			name:           "DuplicateError_ModeFailOverToOriginalValue",
+			data:           data,
+			mode:           ModeFailOverToOriginalValue,
+			policy:         DuplicateError,
+			expectedErr:    true,
+			expectedStruct: firstStruct,
+			expectedMap:    firstMap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := duplicateKeysStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode), WithDuplicateKeys(tt.policy),
+				WithCaseInsensitiveKeys(tt.caseInsensitive))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+	t.Run("DuplicateError_reports_every_duplicate", func(t *testing.T) {
+		s := duplicateKeysStruct{}
+		_, err := Unmarshal([]byte(data), &s, WithMode(ModeAllowMultipleErrors), WithDuplicateKeys(DuplicateError))
+		multipleErr, ok := err.(*MultipleLexerError)
+		if !ok || len(multipleErr.Errors) != 5 {
+			t.Errorf("Unmarshal() unexpected error = %v", err)
+		}
+	})
+}
+
+type invalidUTF8Struct struct {
+	Field string            `json:"field"`
+	M     map[string]string `json:"m"`
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	data := "{\"field\":\"a\xffb\",\"m\":{\"k\xff\":\"v\"},\"u\xff\":\"\\ud800x\",\"pair\":\"\\ud83d\\ude00\",\"ok\":\"\\ufffd\"}"
+	replacedStruct := invalidUTF8Struct{Field: "a\ufffdb", M: map[string]string{"k\ufffd": "v"}}
+	replacedMap := map[string]interface{}{
+		"field":   "a\ufffdb",
+		"m":       map[string]string{"k\ufffd": "v"},
+		"u\ufffd": "\ufffdx",
+		"pair":    "\U0001f600",
+		"ok":      "\ufffd",
+	}
+	tests := []struct {
+		name           string
+		mode           Mode
+		policy         InvalidUTF8Policy
+		expectedErrs   int
+		expectedStruct invalidUTF8Struct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:           "InvalidUTF8Pass",
+			mode:           ModeFailOnFirstError,
+			policy:         InvalidUTF8Pass,
+			expectedErrs:   0,
+			expectedStruct: invalidUTF8Struct{Field: "a\xffb", M: map[string]string{"k\xff": "v"}},
+			expectedMap: map[string]interface{}{
+				"field": "a\xffb",
+				"m":     map[string]string{"k\xff": "v"},
+				"u\xff": "\ufffdx",
+				"pair":  "\U0001f600",
+				"ok":    "\ufffd",
+			},
+		},
+		{
+			name:           "InvalidUTF8Replace",
+			mode:           ModeFailOnFirstError,
+			policy:         InvalidUTF8Replace,
+			expectedErrs:   0,
+			expectedStruct: replacedStruct,
+			expectedMap:    replacedMap,
+		},
+		{
+			name:           "InvalidUTF8Error_ModeFailOnFirstError",
+			mode:           ModeFailOnFirstError,
+			policy:         InvalidUTF8Error,
+			expectedErrs:   1,
+			expectedStruct: invalidUTF8Struct{Field: "a\ufffdb"},
+			expectedMap:    nil,
+		},
+		{
+			name:           "InvalidUTF8Error_ModeAllowMultipleErrors",
+			mode:           ModeAllowMultipleErrors,
+			policy:         InvalidUTF8Error,
+			expectedErrs:   4,
+			expectedStruct: replacedStruct,
+			expectedMap:    replacedMap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := invalidUTF8Struct{}
+			result, err := Unmarshal([]byte(data), &s, WithMode(tt.mode), WithInvalidUTF8(tt.policy))
+			errs := 0
+			if multipleErr, ok := err.(*MultipleLexerError); ok {
+				errs = len(multipleErr.Errors)
+			} else if err != nil {
+				errs = 1
+			}
+			if errs != tt.expectedErrs {
+				t.Errorf("Unmarshal() error = %v, expectedErrs %v", err, tt.expectedErrs)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type decimal struct {
+	Text string
+}
+
+func (d *decimal) UnmarshalJSONFromNumber(number json.Number) error {
+	d.Text = number.String()
+	return nil
+}
+
+type bigNumbersStruct struct {
+	Int        *big.Int   `json:"int"`
+	Float      *big.Float `json:"float"`
+	IntValue   big.Int    `json:"int_value"`
+	Decimal    decimal    `json:"decimal"`
+	DecimalPtr *decimal   `json:"decimal_ptr"`
+}
+
+func TestBigNumbers(t *testing.T) {
+	t.Run("valid_numbers", func(t *testing.T) {
+		data := `{"int":123456789012345678901234567890,"float":1.00000000000000000000000001,` +
+			`"int_value":1000,"decimal":12.50,"decimal_ptr":-0.1}`
+		s := bigNumbersStruct{}
+		result, err := Unmarshal([]byte(data), &s)
+		if err != nil {
+			t.Fatalf("Unmarshal() unexpected error = %v", err)
+		}
+		if s.Int == nil || s.Int.String() != "123456789012345678901234567890" {
+			t.Errorf("Unmarshal() unexpected Int = %v", s.Int)
+		}
+		if s.Float == nil || s.Float.Text('f', 26) != "1.00000000000000000000000001" {
+			t.Errorf("Unmarshal() unexpected Float = %v", s.Float)
+		}
+		if s.IntValue.String() != "1000" {
+			t.Errorf("Unmarshal() unexpected IntValue = %v", s.IntValue.String())
+		}
+		if diff := deep.Equal(s.Decimal, decimal{Text: "12.50"}); diff != nil {
+			t.Errorf("Unmarshal() Decimal mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if diff := deep.Equal(s.DecimalPtr, &decimal{Text: "-0.1"}); diff != nil {
+			t.Errorf("Unmarshal() DecimalPtr mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+		if result["int"] != s.Int || result["float"] != s.Float {
+			t.Errorf("Unmarshal() unexpected result = %v", result)
+		}
+	})
+	tests := []struct {
+		name        string
+		data        string
+		mode        Mode
+		expectedErr bool
+		expectedMap map[string]interface{}
+	}{
+		{
+			name:        "null",
+			data:        `{"int":null,"decimal_ptr":null}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: false,
+			expectedMap: map[string]interface{}{"int": nil, "decimal_ptr": nil},
+		},
+		{
+			name:        "fraction_into_big_int",
+			data:        `{"int":1.5}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: true,
+			expectedMap: nil,
+		},
+		{
+			name:        "exponent_into_big_int",
+			data:        `{"int":1e3}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: true,
+			expectedMap: nil,
+		},
+		{
+			name:        "large_exponent_into_big_int",
+			data:        `{"int":1e300000000}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: true,
+			expectedMap: nil,
+		},
+		{
+			name:        "string_into_big_int",
+			data:        `{"int":"1"}`,
+			mode:        ModeFailOnFirstError,
+			expectedErr: true,
+			expectedMap: nil,
+		},
+		{
+			name:        "invalid_ModeFailOverToOriginalValue",
+			data:        `{"int":1.5,"decimal":"1"}`,
+			mode:        ModeFailOverToOriginalValue,
+			expectedErr: true,
+			expectedMap: map[string]interface{}{"int": 1.5, "decimal": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bigNumbersStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestRawUnknownFields(t *testing.T) {
+	data := `{"field":"a","extra":{"x": [1, 2]},"child":{"field":"b","extra":"y"},"children":[{"field":"c","extra":3}]}`
+	t.Run("test_raw_unknown_fields", func(t *testing.T) {
+		p := extrasParent{}
+		result, err := Unmarshal([]byte(data), &p, WithRawUnknownFields(true), WithNestedResults(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := extrasParent{
+			Field:  "a",
+			Extras: map[string]interface{}{"extra": json.RawMessage(`{"x": [1, 2]}`)},
+			Child: extrasChild{
+				Field:  "b",
+				Extras: map[string]interface{}{"extra": json.RawMessage(`"y"`)},
+			},
+			Children: []*extrasChild{
+				{Field: "c", Extras: map[string]interface{}{"extra": json.RawMessage(`3`)}},
+			},
+		}
+		if diff := deep.Equal(p, expected); diff != nil {
+			t.Errorf("unexpected struct value:\n%s", strings.Join(diff, "\n"))
+		}
+		expectedMap := map[string]interface{}{
+			"field": "a",
+			"extra": json.RawMessage(`{"x": [1, 2]}`),
+			"child": map[string]interface{}{"field": "b", "extra": json.RawMessage(`"y"`)},
+			"children": []interface{}{
+				map[string]interface{}{"field": "c", "extra": json.RawMessage(`3`)},
+			},
+		}
+		if diff := deep.Equal(result, expectedMap); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_raw_unknown_fields_copy", func(t *testing.T) {
+		for _, raw := range []bool{false, true} {
+			input := []byte(`{"field":"a","extra":"value"}`)
+			p := extrasParent{}
+			_, err := Unmarshal(input, &p, WithRawUnknownFields(raw))
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			copy(input, `{"FIELD":"A","EXTRA":"VALUE"}`)
+			extra := interface{}("value")
+			if raw {
+				extra = json.RawMessage(`"value"`)
+			}
+			if diff := deep.Equal(p.Extras, map[string]interface{}{"extra": extra}); diff != nil {
+				t.Errorf("unexpected extras:\n%s", strings.Join(diff, "\n"))
+			}
+		}
+	})
+	t.Run("test_raw_unknown_fields_zero_copy", func(t *testing.T) {
+		input := []byte(`{"extra":"value"}`)
+		result, err := Unmarshal(input, &extrasParent{}, WithRawUnknownFields(true), WithZeroCopyRawUnknownFields(true))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		copy(input[len(`{"extra":"`):], "VALUE")
+		if diff := deep.Equal(result, map[string]interface{}{"extra": json.RawMessage(`"VALUE"`)}); diff != nil {
+			t.Errorf("unexpected result map:\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_raw_unknown_fields_invalid_json", func(t *testing.T) {
+		_, err := Unmarshal([]byte(`{"extra":{"x":}}`), &extrasParent{}, WithRawUnknownFields(true))
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestLazyUnknownFields(t *testing.T) {
+	data := `{"field":"a","extra":{"x":[1,2]},"child":{"field":"b","extra":"y"}}`
+	t.Run("test_lazy_unknown_fields", func(t *testing.T) {
+		p := extrasParent{}
+		result, err := Unmarshal([]byte(data), &p, WithLazyUnknownFields(true), WithNumberMode(NumberModeJSONNumber))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		lazy, ok := result["extra"].(*Lazy)
+		if !ok || p.Extras["extra"] != lazy {
+			t.Fatalf("unexpected result map %+v", result)
+		}
+		if string(lazy.Raw()) != `{"x":[1,2]}` {
+			t.Errorf("unexpected raw value %s", lazy.Raw())
+		}
+		value, err := lazy.Value()
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := map[string]interface{}{"x": []interface{}{json.Number("1"), json.Number("2")}}
+		if diff := deep.Equal(value, expected); diff != nil {
+			t.Errorf("unexpected lazy value:\n%s", strings.Join(diff, "\n"))
+		}
+		cached, _ := lazy.Value()
+		if reflect.ValueOf(cached).Pointer() != reflect.ValueOf(value).Pointer() {
+			t.Errorf("lazy value was not cached")
+		}
+		var typed struct {
+			X []int `json:"x"`
+		}
+		if err = lazy.As(&typed); err != nil || len(typed.X) != 2 || typed.X[1] != 2 {
+			t.Errorf("unexpected As() result %+v, error %v", typed, err)
+		}
+		childExtra, ok := p.Child.Extras["extra"].(*Lazy)
+		if !ok || string(childExtra.Raw()) != `"y"` {
+			t.Errorf("unexpected child extras %+v", p.Child.Extras)
+		}
+		marshalled, err := json.Marshal(result)
+		if err != nil || string(marshalled) != `{"child":{"field":"b"},"extra":{"x":[1,2]},"field":"a"}` {
+			t.Errorf("unexpected marshalled result %s, error %v", marshalled, err)
+		}
+	})
+	t.Run("test_lazy_unknown_fields_as", func(t *testing.T) {
+		result, err := Unmarshal([]byte(`{"extra":{"x":9007199254740993,"y":"a"},"dup":{"x":1,"x":2}}`), &extrasParent{},
+			WithLazyUnknownFields(true), WithNumberMode(NumberModeJSONNumber), WithDuplicateKeys(DuplicateFirst))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		lazy := result["extra"].(*Lazy)
+		var value interface{}
+		if err = lazy.As(&value); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		expected := map[string]interface{}{"x": json.Number("9007199254740993"), "y": "a"}
+		if diff := deep.Equal(value, expected); diff != nil {
+			t.Errorf("unexpected As() value:\n%s", strings.Join(diff, "\n"))
+		}
+		var typed struct {
+			X int64 `json:"x"`
+		}
+		if err = lazy.As(&typed); err != nil || typed.X != 9007199254740993 {
+			t.Errorf("unexpected As() result %+v, error %v", typed, err)
+		}
+		var dup map[string]int
+		if err = result["dup"].(*Lazy).As(&dup); err != nil || dup["x"] != 1 {
+			t.Errorf("unexpected As() result %+v, error %v", dup, err)
+		}
+		var invalid map[string]int
+		if err = lazy.As(&invalid); err == nil {
+			t.Errorf("expected As() error")
+		}
+		if err = lazy.As(typed); err != ErrInvalidValue {
+			t.Errorf("unexpected As() error %v", err)
+		}
+	})
+	t.Run("test_lazy_unknown_fields_decode_error", func(t *testing.T) {
+		result, err := Unmarshal([]byte("{\"extra\":\"a\xffb\"}"), &extrasParent{},
+			WithLazyUnknownFields(true), WithInvalidUTF8(InvalidUTF8Error))
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		value, err := result["extra"].(*Lazy).Value()
+		if err == nil || value != nil {
+			t.Errorf("unexpected lazy value %v, error %v", value, err)
+		}
+	})
+}
+
+type resultContentChild struct {
+	Field string `json:"field"`
+}
+
+type resultContentStruct struct {
+	Field string             `json:"field"`
+	Child resultContentChild `json:"child"`
+}
+
+func TestResultContent(t *testing.T) {
+	data := `{"field":"a","child":{"field":"b","extra":2},"extra":1}`
+	fullStruct := resultContentStruct{Field: "a", Child: resultContentChild{Field: "b"}}
+	tests := []struct {
+		name           string
+		data           string
+		mode           Mode
+		content        ResultContent
+		nestedResults  bool
+		expectedErr    bool
+		expectedStruct resultContentStruct
+		expectedMap    map[string]interface{}
+	}{
+		{
+			name:           "ResultAll",
+			data:           data,
+			mode:           ModeFailOnFirstError,
+			content:        ResultAll,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap:    map[string]interface{}{"field": "a", "child": fullStruct.Child, "extra": float64(1)},
+		},
+		{
+			name:           "ResultUnknownOnly",
+			data:           data,
+			mode:           ModeFailOnFirstError,
+			content:        ResultUnknownOnly,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap:    map[string]interface{}{"extra": float64(1)},
+		},
+		{
+			name:           "ResultKnownOnly",
+			data:           data,
+			mode:           ModeFailOnFirstError,
+			content:        ResultKnownOnly,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap:    map[string]interface{}{"field": "a", "child": fullStruct.Child},
+		},
+		{
+			name:           "ResultKnownOnly_nested_results",
+			data:           data,
+			mode:           ModeFailOnFirstError,
+			content:        ResultKnownOnly,
+			nestedResults:  true,
+			expectedErr:    false,
+			expectedStruct: fullStruct,
+			expectedMap: map[string]interface{}{
+				"field": "a",
+				"child": map[string]interface{}{"field": "b"},
+			},
+		},
+		{
+			name:           "ResultUnknownOnly_ModeFailOverToOriginalValue",
+			data:           `{"field":1,"child":{"field":2},"extra":1}`,
+			mode:           ModeFailOverToOriginalValue,
+			content:        ResultUnknownOnly,
+			expectedErr:    true,
+			expectedStruct: resultContentStruct{},
+			expectedMap:    map[string]interface{}{"extra": float64(1)},
+		},
+		{
+			name:           "ResultKnownOnly_ModeFailOverToOriginalValue",
+			data:           `{"field":1,"child":{"field":2,"extra":2},"extra":1}`,
+			mode:           ModeFailOverToOriginalValue,
+			content:        ResultKnownOnly,
+			expectedErr:    true,
+			expectedStruct: resultContentStruct{},
+			expectedMap: map[string]interface{}{
+				"field": float64(1),
+				"child": map[string]interface{}{"field": float64(2), "extra": float64(2)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := resultContentStruct{}
+			result, err := Unmarshal([]byte(tt.data), &s, WithMode(tt.mode), WithResultContent(tt.content),
+				WithNestedResults(tt.nestedResults))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Unmarshal() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if diff := deep.Equal(s, tt.expectedStruct); diff != nil {
+				t.Errorf("Unmarshal() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+			if diff := deep.Equal(result, tt.expectedMap); diff != nil {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+type nativeChild struct {
+	Field  int                    `json:"field"`
+	Extras map[string]interface{} `json:"-" marshmallow:",extras"`
+}
+
+type nativeStruct struct {
+	Int      int            `json:"int"`
+	Ints     []int          `json:"ints"`
+	Uint8s   [2]uint8       `json:"uint8s"`
+	Float32  float32        `json:"float32"`
+	Ptr      *string        `json:"ptr"`
+	Child    nativeChild    `json:"child"`
+	Children []*nativeChild `json:"children"`
+	Map      map[int]bool   `json:"map"`
+	Bytes    []byte         `json:"bytes"`
+	Null     *int           `json:"null"`
+}
+
+const nativeData = `{"int":1,"ints":[1,2],"uint8s":[3,4],"float32":1.5,"ptr":"p","child":{"field":1,"extra":"x"},` +
+	`"children":[{"field":2},null],"map":{"1":true},"bytes":"Zm9v","null":null,"unknown":{"a":[1]}}`
+
+func TestNativeResultTypes(t *testing.T) {
+	expectedMap := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(nativeData), &expectedMap); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, nestedResults := range []bool{false, true} {
+		t.Run(fmt.Sprintf("nested_results_%v", nestedResults), func(t *testing.T) {
+			s := nativeStruct{}
+			result, err := Unmarshal([]byte(nativeData), &s, WithNativeResultTypes(true), WithNestedResults(nestedResults))
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(result, expectedMap) {
+				t.Errorf("Unmarshal() map mismatch (actual, expected):\n%+v\n%+v", result, expectedMap)
+			}
+			fromMap := nativeStruct{}
+			if _, err = UnmarshalFromJSONMap(result, &fromMap); err != nil {
+				t.Errorf("UnmarshalFromJSONMap() unexpected error %v", err)
+			}
+			if diff := deep.Equal(fromMap, s); diff != nil {
+				t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+	t.Run("failover_containers", func(t *testing.T) {
+		s := struct {
+			List []int          `json:"l"`
+			Map  map[string]int `json:"m"`
+		}{}
+		result, err := Unmarshal([]byte(`{"l":[1,"x",3],"m":{"a":1,"b":"x"}}`), &s,
+			WithMode(ModeFailOverToOriginalValue), WithNativeResultTypes(true))
+		if err == nil {
+			t.Errorf("Unmarshal() expected error")
+		}
+		expected := map[string]interface{}{
+			"l": []interface{}{float64(1), "x", float64(3)},
+			"m": map[string]interface{}{"a": float64(1), "b": "x"},
+		}
+		if diff := deep.Equal(result, expected); diff != nil {
+			t.Errorf("Unmarshal() map mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("number_modes", func(t *testing.T) {
+		data := `{"int":9007199254740993,"uint":18446744073709551615,"float":2,"list":[1,2.5],"unknown":3}`
+		tests := []struct {
+			mode     NumberMode
+			expected map[string]interface{}
+		}{
+			{
+				mode: NumberModeFloat64,
+				expected: map[string]interface{}{
+					"int":     float64(9007199254740993),
+					"uint":    float64(18446744073709551615),
+					"float":   float64(2),
+					"list":    []interface{}{float64(1), 2.5},
+					"unknown": float64(3),
+				},
+			},
+			{
+				mode: NumberModeJSONNumber,
+				expected: map[string]interface{}{
+					"int":     json.Number("9007199254740993"),
+					"uint":    json.Number("18446744073709551615"),
+					"float":   json.Number("2"),
+					"list":    []interface{}{json.Number("1"), json.Number("2.5")},
+					"unknown": json.Number("3"),
+				},
+			},
+			{
+				mode: NumberModeInt64IfIntegral,
+				expected: map[string]interface{}{
+					"int":     int64(9007199254740993),
+					"uint":    float64(18446744073709551615),
+					"float":   int64(2),
+					"list":    []interface{}{int64(1), 2.5},
+					"unknown": int64(3),
+				},
+			},
+		}
+		for _, tt := range tests {
+			s := struct {
+				Int   int64     `json:"int"`
+				Uint  uint64    `json:"uint"`
+				Float float64   `json:"float"`
+				List  []float32 `json:"list"`
+			}{}
+			result, err := Unmarshal([]byte(data), &s, WithNativeResultTypes(true), WithNumberMode(tt.mode))
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error %v", err)
+			}
+			if diff := deep.Equal(result, tt.expected); diff != nil {
+				t.Errorf("Unmarshal() mode %d map mismatch (actual, expected):\n%s", tt.mode, strings.Join(diff, "\n"))
+			}
+		}
+	})
+	t.Run("unsupported_type", func(t *testing.T) {
+		s := struct {
+			Field complex64 `json:"field"`
+		}{}
+		_, err := Unmarshal([]byte(`{"field":1}`), &s, WithNativeResultTypes(true))
+		if err == nil {
+			t.Errorf("Unmarshal() expected error")
+		}
+	})
+}
+
+func TestUnmarshalOrdered(t *testing.T) {
+	data := `{"z":1,"child":{"field":"b","y":2,"extra":3},"children":[{"x":1,"field":"c"}],` +
+		`"field":"a","unknown":{"b":[{"d":1,"c":2}],"a":null}}`
+	t.Run("test_ordered_result", func(t *testing.T) {
+		p := extrasParent{}
+		result, err := UnmarshalOrdered([]byte(data), &p)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if diff := deep.Equal(result.Keys(), []string{"z", "child", "children", "field", "unknown"}); diff != nil {
+			t.Errorf("unexpected keys:\n%s", strings.Join(diff, "\n"))
+		}
+		marshalled, err := json.Marshal(result)
+		expected := `{"z":1,"child":{"field":"b"},"children":[{"field":"c"}],` +
+			`"field":"a","unknown":{"b":[{"d":1,"c":2}],"a":null}}`
+		if err != nil || string(marshalled) != expected {
+			t.Errorf("unexpected marshalled result %s, error %v", marshalled, err)
+		}
+		if p.Field != "a" || p.Child.Field != "b" {
+			t.Errorf("unexpected struct %+v", p)
+		}
+	})
+	t.Run("test_ordered_nested_results", func(t *testing.T) {
+		p := extrasParent{}
+		result, err := UnmarshalOrdered([]byte(data), &p, WithNestedResults(true), WithNativeResultTypes(true))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		marshalled, err := json.Marshal(result)
+		if err != nil || string(marshalled) != data {
+			t.Errorf("unexpected marshalled result %s, error %v", marshalled, err)
+		}
+		fromMap := extrasParent{}
+		if _, err = UnmarshalFromJSONMap(result.Map(), &fromMap); err != nil {
+			t.Errorf("UnmarshalFromJSONMap() unexpected error %v", err)
+		}
+		if diff := deep.Equal(fromMap, p); diff != nil {
+			t.Errorf("UnmarshalFromJSONMap() struct mismatch (actual, expected):\n%s", strings.Join(diff, "\n"))
+		}
+	})
+	t.Run("test_ordered_fail_over", func(t *testing.T) {
+		failOverData := `{"c":{"z":{"y":1,"x":2},"b":"x","a":"v","w":3},"m":{"z":1,"y":"x","x":{"b":1,"a":2},"w":2}}`
+		for _, nestedResults := range []bool{false, true} {
+			s := struct {
+				Child struct {
+					A string `json:"a"`
+					B int    `json:"b"`
+				} `json:"c"`
+				Map map[string]int `json:"m"`
+			}{}
+			result, err := UnmarshalOrdered([]byte(failOverData), &s,
+				WithMode(ModeFailOverToOriginalValue), WithNestedResults(nestedResults))
+			if err == nil {
+				t.Errorf("expected error")
+			}
+			marshalled, err := json.Marshal(result)
+			if err != nil || string(marshalled) != failOverData {
+				t.Errorf("nested results %v: unexpected marshalled result %s, error %v", nestedResults, marshalled, err)
+			}
+		}
+	})
+	t.Run("test_ordered_invalid_input", func(t *testing.T) {
+		result, err := UnmarshalOrdered([]byte(`[]`), &extrasParent{})
+		if err != ErrInvalidInput || result != nil {
+			t.Errorf("unexpected result %v, error %v", result, err)
+		}
+	})
+}